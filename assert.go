@@ -0,0 +1,31 @@
+package sanepanic
+
+import (
+	"fmt"
+)
+
+// An AssertionError is the panic value of a failed Assert or Assertf, so HandlerFuncs can tell assertion failures
+// apart from other panics.
+type AssertionError struct {
+	Message string
+}
+
+func (e *AssertionError) Error() string {
+	return "assertion failed: " + e.Message
+}
+
+// Panics with an *AssertionError carrying msg if cond is false. Combined with Forward this gives a consistent way
+// of checking invariants, with failures going through the central handler. Since sanepanic's own frames are skipped,
+// the panic is reported as coming from the caller.
+func Assert(cond bool, msg string) {
+	if !cond {
+		panic(&AssertionError{Message: msg})
+	}
+}
+
+// The same as Assert, with the message formatted as with fmt.Sprintf.
+func Assertf(cond bool, format string, args ...interface{}) {
+	if !cond {
+		panic(&AssertionError{Message: fmt.Sprintf(format, args...)})
+	}
+}
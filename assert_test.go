@@ -0,0 +1,47 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAssert(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	ph.Protect(func() {
+		sanepanic.Assertf(1+1 == 3, "expected %d", 3)
+	})
+
+	info := <-handled
+	err, ok := sanepanic.As[*sanepanic.AssertionError](info)
+	if !ok {
+		t.Fatalf("Expected an *AssertionError, got %v", info.Info)
+	}
+	if err.Message != "expected 3" || err.Error() != "assertion failed: expected 3" {
+		t.Errorf("Unexpected assertion message %q", err.Error())
+	}
+	if !strings.Contains(info.Function, "TestAssert") {
+		t.Errorf("Expected the failure to be reported from TestAssert, got %q", info.Function)
+	}
+}
+
+func TestAssertPasses(t *testing.T) {
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		t.Errorf("Passing assertion reached the handler: %v", info.Info)
+		return true
+	})
+	defer ph.Done()
+
+	ph.Protect(func() {
+		sanepanic.Assert(true, "Should pass")
+		sanepanic.Assertf(true, "Should %s", "pass")
+	})
+	ph.Drain(time.Second)
+}
@@ -0,0 +1,111 @@
+package sanepanic
+
+import (
+	"math/rand"
+	"time"
+)
+
+// The longest the package waits before an automatic restart, however many there have been.
+const maxAutoRestartBackoff = 5 * time.Minute
+
+var (
+	maxAutoRestarts    int
+	autoRestartBackoff time.Duration
+	autoRestarts       int
+	stopWatching       chan struct{} // Closed to retire the goroutine watching the current handler
+)
+
+// Makes the package restart its listener automatically when the HandlerFunc returns false, rather than requiring
+// a call to Restart. It will restart up to maxRestarts times, waiting backoff before the first restart and twice
+// as long before each one after that, up to 5 minutes (plus some jitter so a flaky HandlerFunc can't busy-loop),
+// before giving up. A backoff of zero or less restarts straight away.
+//
+// Panics forwarded while waiting to restart pass silently, as with a stopped listener. Calling SetAutoRestart again resets
+// the restart count, and a maxRestarts of 0 turns automatic restarting off.
+func SetAutoRestart(maxRestarts int, backoff time.Duration) {
+	mu.Lock()
+	defer mu.Unlock()
+	if backoff < 0 {
+		backoff = 0
+	}
+	maxAutoRestarts = maxRestarts
+	autoRestartBackoff = backoff
+	autoRestarts = 0
+	watchForStop()
+}
+
+// Starts watching the current handler so it can be restarted if it stops, retiring whatever was watching before.
+// Expects mu to be held.
+func watchForStop() {
+	stopWatchingForStop()
+	if maxAutoRestarts <= 0 {
+		return
+	}
+
+	stopWatching = make(chan struct{})
+	go autoRestart(internalPanicHandler, stopWatching)
+}
+
+// Retires the goroutine watching the current handler, if any. Expects mu to be held.
+func stopWatchingForStop() {
+	if stopWatching != nil {
+		close(stopWatching)
+		stopWatching = nil
+	}
+}
+
+func autoRestart(ph *Handler, stop chan struct{}) {
+	select {
+	case <-ph.quit:
+	case <-stop:
+		return
+	}
+
+	mu.Lock()
+	if stopped(stop) || autoRestarts >= maxAutoRestarts {
+		mu.Unlock()
+		return
+	}
+	autoRestarts++
+	delay := autoRestartDelay(autoRestartBackoff, autoRestarts)
+	mu.Unlock()
+
+	select {
+	case <-time.After(delay):
+	case <-stop:
+		return
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !stopped(stop) {
+		restart()
+		watchForStop()
+	}
+}
+
+// How long to wait before the nth restart: backoff doubled for each restart before it, capped so it can't overflow,
+// plus up to half as much again as jitter.
+func autoRestartDelay(backoff time.Duration, n int) time.Duration {
+	delay := backoff
+	for i := 1; i < n && delay < maxAutoRestartBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxAutoRestartBackoff {
+		delay = maxAutoRestartBackoff
+	}
+	if delay > 0 {
+		delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	}
+	return delay
+}
+
+// Whether the watcher has been retired, e.g. by a manual Restart or Done while it was waiting for the lock.
+func stopped(stop chan struct{}) bool {
+	select {
+	case <-stop:
+		return true
+	default:
+		return false
+	}
+}
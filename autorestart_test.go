@@ -0,0 +1,79 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAutoRestart(t *testing.T) {
+	const backoff = 20 * time.Millisecond
+
+	previous := sanepanic.CurrentHandlerFunc()
+	defer sanepanic.Restart()
+	defer sanepanic.SetHandlerFunc(previous)
+	defer sanepanic.SetAutoRestart(0, 0)
+
+	mu := &sync.Mutex{}
+	calls := []time.Time{}
+	sanepanic.SetHandlerFunc(func(sanepanic.Info) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, time.Now())
+		return false
+	})
+	sanepanic.Restart() // Earlier tests may have left the listener stopped
+	sanepanic.SetAutoRestart(3, backoff)
+
+	// Keep panicking well past the point the last restart could have happened
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		sanepanic.ForwardValueSync("Oh no!")
+		time.Sleep(time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(calls) != 4 {
+		t.Fatalf("Expected the handler to run once and then after each of 3 restarts, ran %d times", len(calls))
+	}
+	for i := 1; i < len(calls); i++ {
+		minDelay := backoff << uint(i-1)
+		if delay := calls[i].Sub(calls[i-1]); delay < minDelay {
+			t.Errorf("Restart %d happened after %v, expected at least %v", i, delay, minDelay)
+		}
+	}
+}
+
+func TestAutoRestartManyRestarts(t *testing.T) {
+	previous := sanepanic.CurrentHandlerFunc()
+	defer sanepanic.Restart()
+	defer sanepanic.SetHandlerFunc(previous)
+	defer sanepanic.SetAutoRestart(0, 0)
+
+	for _, backoff := range []time.Duration{-time.Second, 0, time.Nanosecond} {
+		mu := &sync.Mutex{}
+		calls := 0
+		sanepanic.SetHandlerFunc(func(sanepanic.Info) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			return false
+		})
+		sanepanic.Restart()
+		sanepanic.SetAutoRestart(1000, backoff)
+
+		// A panic in the restarting goroutine would take the whole test binary down
+		deadline := time.Now().Add(200 * time.Millisecond)
+		for time.Now().Before(deadline) {
+			sanepanic.ForwardValueSync("Oh no!")
+		}
+
+		mu.Lock()
+		if calls < 2 {
+			t.Errorf("Expected a backoff of %v to restart the listener, the handler ran %d times", backoff, calls)
+		}
+		mu.Unlock()
+	}
+}
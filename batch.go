@@ -0,0 +1,123 @@
+package sanepanic
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Creates a new panic handler that delivers panics in batches rather than one at a time, AND makes it start listening.
+// Once a panic arrives, every panic forwarded within the following window is collected and passed to handler
+// together. A lone panic is still delivered once the window is up. As with a HandlerFunc, the handler keeps listening
+// as long as handler returns true.
+//
+// This is useful when a single failure tends to set off a cascade of panics, and you'd rather report them as a
+// whole. The batch function takes the place of the HandlerFunc, so registered HandlerFuncs aren't run. Installing a
+// HandlerFunc afterwards (with SetHandlerFunc, SwapHandlerFunc, or by falling back once handler returns false) replaces
+// the batch function: panics are still collected over the window, but are then handled one at a time like on any
+// other handler. Batch handlers can be paused and drained like any other handler too.
+func NewBatchHandler(handler func([]Info) bool, window time.Duration) *Handler {
+	ph := &Handler{
+		// Lets the batch function stand in wherever a HandlerFunc is expected
+		handle: func(info Info) bool {
+			return handler([]Info{info})
+		},
+		batch: handler,
+		listenWith: func(ph *Handler) {
+			ph.listenBatched(window)
+		},
+	}
+	ph.start()
+	return ph
+}
+
+func (ph *Handler) listenBatched(window time.Duration) {
+	defer close(ph.quit)
+	atomic.StoreUint64(&ph.listener, currentGoroutineID())
+	for {
+		// A drain that times out while a batch is being collected drops the batch. The count is read before checking
+		// for a dropper, which is stored before the count is bumped, so a drain timing out in between can't be missed.
+		var batch []forwardedPanic
+		var started uint64
+		select {
+		case fp := <-ph.panicChan:
+			started = atomic.LoadUint64(&ph.timeouts)
+			if ph.dropIfDraining(fp) || ph.answerProbe(fp) || ph.hold(fp) {
+				continue
+			}
+			batch = append(ph.release(), fp)
+		case <-ph.resumed:
+			started = atomic.LoadUint64(&ph.timeouts)
+			if batch = ph.release(); len(batch) == 0 {
+				continue
+			}
+		case <-ph.stop:
+			if held := ph.releaseAll(); len(held) > 0 { // Even if we're paused
+				ph.handleBatch(held)
+			}
+			return
+		}
+
+		timer := time.NewTimer(window)
+		for collecting := true; collecting; {
+			select {
+			case fp := <-ph.panicChan:
+				if !ph.dropIfDraining(fp) && !ph.answerProbe(fp) && !ph.hold(fp) {
+					batch = append(batch, fp)
+				}
+			case <-timer.C:
+				collecting = false
+			case <-ph.stop:
+				collecting = false // Deliver what we have before stopping
+			}
+			if ph.dropIfStale(batch, started) {
+				batch, collecting = nil, false
+			}
+		}
+		timer.Stop()
+
+		if len(batch) == 0 {
+			continue
+		}
+
+		if !ph.handleBatch(batch) {
+			for _, dropped := range ph.releaseAll() {
+				ph.settle(dropped)
+			}
+			return
+		}
+	}
+}
+
+// Passes the batch to the batch function, or to the HandlerFuncs one at a time if one has replaced it, falling back if
+// they give up. Returns false if the listener should stop.
+func (ph *Handler) handleBatch(batch []forwardedPanic) bool {
+	ph.mu.Lock()
+	batched := ph.batch != nil
+	ph.mu.Unlock()
+	if !batched {
+		return ph.handleAll(batch)
+	}
+	return ph.runBatch(batch) || ph.fallBack()
+}
+
+// Hands the whole batch to the batch function. Waiters are woken with the first panic of the batch, since each waiter
+// only ever receives one panic.
+func (ph *Handler) runBatch(batch []forwardedPanic) bool {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+
+	infos := make([]Info, len(batch))
+	for i, fp := range batch {
+		defer ph.settle(fp)
+		ph.breaker.record(time.Now())
+		infos[i] = ph.observe(fp.info)
+	}
+
+	ph.flush()
+	keepHandling := ph.batch(infos)
+	for _, info := range infos {
+		ph.history.add(info)
+	}
+	ph.notifyWaiters(infos[0])
+	return keepHandling
+}
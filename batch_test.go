@@ -0,0 +1,195 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBatchHandler(t *testing.T) {
+	batches := make(chan []sanepanic.Info, 1)
+	ph := sanepanic.NewBatchHandler(func(infos []sanepanic.Info) bool {
+		batches <- infos
+		return true
+	}, 200*time.Millisecond)
+	defer ph.Done()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			defer ph.ForwardAndDone(wg)
+			panic("Arghlbarg")
+		}()
+	}
+	wg.Wait()
+
+	batch := <-batches
+	if len(batch) != 5 {
+		t.Fatalf("Expected all 5 panics in one batch, got %d", len(batch))
+	}
+	for _, info := range batch {
+		if info.Info != "Arghlbarg" {
+			t.Errorf("Unexpected panic value %v", info.Info)
+		}
+	}
+}
+
+func TestBatchHandlerSinglePanic(t *testing.T) {
+	const window = 50 * time.Millisecond
+	var batch []sanepanic.Info
+	ph := sanepanic.NewBatchHandler(func(infos []sanepanic.Info) bool {
+		batch = infos
+		return true
+	}, window)
+	defer ph.Done()
+
+	start := time.Now()
+	ph.ForwardValueSync("Oh no!")
+
+	if elapsed := time.Since(start); elapsed < window {
+		t.Errorf("Batch was delivered after %v, before the window was up", elapsed)
+	}
+	if len(batch) != 1 || batch[0].Info != "Oh no!" {
+		t.Errorf("Expected a batch with just the one panic, got %v", batch)
+	}
+}
+
+func TestBatchHandlerSetHandlerFunc(t *testing.T) {
+	batches := 0
+	ph := sanepanic.NewBatchHandler(func(infos []sanepanic.Info) bool {
+		batches++
+		return true
+	}, time.Millisecond)
+	defer ph.Done()
+
+	handled := []interface{}{}
+	old := ph.SwapHandlerFunc(func(info sanepanic.Info) bool {
+		handled = append(handled, info.Info)
+		return true
+	})
+	ph.ForwardValueSync("Oh no!")
+	if batches != 0 || len(handled) != 1 || handled[0] != "Oh no!" {
+		t.Errorf("Expected the new HandlerFunc to replace the batch function, got %d batches and %v", batches, handled)
+	}
+
+	// The old HandlerFunc stands in for the batch function
+	ph.SetHandlerFunc(old)
+	ph.ForwardValueSync("Oh no!")
+	if batches != 1 {
+		t.Errorf("Expected reinstalling the old HandlerFunc to deliver to the batch function, got %d batches", batches)
+	}
+}
+
+func TestBatchHandlerStopsAtFirstFalse(t *testing.T) {
+	ph := sanepanic.NewBatchHandler(func(infos []sanepanic.Info) bool {
+		return true
+	}, 200*time.Millisecond)
+	defer ph.Done()
+
+	handled := 0
+	ph.SetHandlerFunc(func(info sanepanic.Info) bool {
+		handled++
+		return false
+	})
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			defer wg.Done()
+			ph.ForwardValueSync("Arghlbarg") // Returns once the panic has been settled, handled or not
+		}()
+	}
+	wg.Wait()
+
+	if handled != 1 {
+		t.Errorf("Expected the batch to stop being handled once the HandlerFunc returned false, it handled %d panics", handled)
+	}
+}
+
+func TestBatchHandlerFallback(t *testing.T) {
+	ph := sanepanic.NewBatchHandler(func(infos []sanepanic.Info) bool {
+		return false
+	}, time.Millisecond)
+	defer ph.Done()
+
+	handled := make(chan interface{}, 1)
+	ph.SetFallbackHandlerFunc(func(info sanepanic.Info) bool {
+		handled <- info.Info
+		return true
+	})
+
+	ph.ForwardValueSync("First")
+	ph.ForwardValueSync("Second")
+	select {
+	case v := <-handled:
+		if v != "Second" {
+			t.Errorf("Expected the fallback to handle the second panic, it handled %v", v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Fallback HandlerFunc never took over from the batch function")
+	}
+}
+
+func TestBatchHandlerPause(t *testing.T) {
+	batches := make(chan []sanepanic.Info, 2)
+	ph := sanepanic.NewBatchHandler(func(infos []sanepanic.Info) bool {
+		batches <- infos
+		return true
+	}, 10*time.Millisecond)
+	defer ph.Done()
+
+	ph.Pause()
+	ph.ForwardValue("One")
+	ph.ForwardValue("Two")
+	select {
+	case batch := <-batches:
+		t.Fatalf("Paused batch handler delivered %v", batch)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	ph.Resume()
+	batch := <-batches
+	if len(batch) != 2 {
+		t.Errorf("Expected both held panics in one batch after resuming, got %v", batch)
+	}
+}
+
+func TestBatchHandlerDrainWhileCollecting(t *testing.T) {
+	ph := sanepanic.NewBatchHandler(func(infos []sanepanic.Info) bool {
+		time.Sleep(100 * time.Millisecond)
+		return true
+	}, time.Second)
+	defer ph.Done()
+
+	// The first panic starts a long window, which the rest arrive in
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ph.ForwardValueSync(i)
+		}(i)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu := &sync.Mutex{}
+	dropped := 0
+	if ph.DrainWithCallback(10*time.Millisecond, func(sanepanic.Info) {
+		mu.Lock()
+		defer mu.Unlock()
+		dropped++
+	}) {
+		t.Errorf("Drain reported every panic was handled in time")
+	}
+	ph.ForwardValue("During the window")
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if dropped == 0 {
+		t.Errorf("No panics were dropped")
+	}
+}
@@ -0,0 +1,85 @@
+package sanepanic
+
+import (
+	"runtime"
+	"strings"
+)
+
+// The import path of this package followed by a dot, e.g. "github.com/Jragonmiris/sanepanic."
+var packagePrefix = func() string {
+	pc, _, _, _ := runtime.Caller(0)
+	name := runtime.FuncForPC(pc).Name()
+	slash := strings.LastIndex(name, "/")
+	return name[:slash+strings.Index(name[slash:], ".")+1]
+}()
+
+// Walks up the stack to find the function that panicked: the first function past the runtime's panic machinery that
+// isn't part of sanepanic. If we aren't panicking it's the first function outside sanepanic, i.e. whoever forwarded the value.
+// Skip is the number of further frames to pass over, for wrappers that don't want to be reported as the origin.
+func panickingFunction(skip int) string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	function, skipped := "", 0
+	for {
+		frame, more := frames.Next()
+		if frame.Function == "runtime.gopanic" {
+			// Whatever we found was a deferred function recovering the panic, the culprit is further up
+			function, skipped = "", 0
+		} else if function == "" && !isInternalFrame(frame.Function) {
+			if skipped < skip {
+				skipped++
+			} else {
+				function = frame.Function
+			}
+		}
+		if !more {
+			return function
+		}
+	}
+}
+
+func isInternalFrame(function string) bool {
+	return strings.HasPrefix(function, packagePrefix) ||
+		strings.HasPrefix(function, "runtime.") ||
+		strings.Contains(function, ".deferwrap") // The compiler wraps deferred calls that take arguments
+}
+
+// Removes the frames above function from the first goroutine in a runtime.Stack trace, i.e. sanepanic's own frames,
+// the runtime's panic machinery and anything that was skipped. The trace is left alone if function can't be found.
+func trimStack(stack, function string) string {
+	header := strings.IndexByte(stack, '\n') + 1
+	if function == "" || header == 0 {
+		return stack
+	}
+
+	// Only look in the first goroutine, goroutines are separated by blank lines
+	first := stack[header:]
+	if end := strings.Index(first, "\n\n"); end >= 0 {
+		first = first[:end]
+	}
+	frame := strings.Index(first, "\n"+function+"(")
+	if frame < 0 {
+		return stack
+	}
+	return stack[:header] + stack[header+frame+1:]
+}
+
+// Does the same job as panickingFunction for a stack trace in text form: the first function in the first goroutine
+// below the last call to panic that isn't part of sanepanic or the runtime.
+func stackFunction(stack string) string {
+	lines := strings.Split(stack, "\n")
+	function := ""
+	for i := 1; i+1 < len(lines) && lines[i] != ""; i += 2 { // Skip the "goroutine N" header, stop at the first blank line
+		name := lines[i]
+		if paren := strings.LastIndex(name, "("); paren > 0 {
+			name = name[:paren]
+		}
+		if name == "panic" {
+			function = "" // Anything we found was handling the panic, the culprit is further down
+		} else if function == "" && !isInternalFrame(name) && !strings.HasPrefix(name, "runtime/debug.") {
+			function = name
+		}
+	}
+	return function
+}
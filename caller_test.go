@@ -0,0 +1,55 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func panicInNamedFunction() {
+	panic("Oh no!")
+}
+
+func TestFunction(t *testing.T) {
+	functions := make(chan string, 2)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		functions <- info.Function
+		return true
+	})
+	defer ph.Done()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer ph.Forward()
+		panicInNamedFunction()
+	}()
+	go func() {
+		defer ph.ForwardAndDone(wg)
+		panicInNamedFunction()
+	}()
+	wg.Wait()
+
+	for i := 0; i < 2; i++ {
+		if function := <-functions; !strings.HasSuffix(function, ".panicInNamedFunction") {
+			t.Errorf("Expected the panic to originate in panicInNamedFunction, got %q", function)
+		}
+	}
+}
+
+func TestFunctionForwardValue(t *testing.T) {
+	var function string
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		function = info.Function
+		return true
+	})
+	defer ph.Done()
+
+	ph.ForwardValueSync("Oh no!")
+
+	if !strings.HasSuffix(function, ".TestFunctionForwardValue") {
+		t.Errorf("Expected the value to be forwarded from TestFunctionForwardValue, got %q", function)
+	}
+}
@@ -0,0 +1,232 @@
+package sanepanic
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Settings for how a Handler captures the Info for a panic.
+type captureOptions struct {
+	stackSkip       int
+	goroutineStates bool
+	maxStackLength  int
+}
+
+func (ph *Handler) captureOptions() captureOptions {
+	ph.captureMu.Lock()
+	defer ph.captureMu.Unlock()
+	return ph.capture
+}
+
+// Sets how many frames, beyond sanepanic's own, are trimmed from the top of stack traces captured by the package's
+// listener. See Handler.SetStackSkip.
+func SetStackSkip(skip int) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.SetStackSkip(skip)
+}
+
+// Sets how many frames, beyond sanepanic's own, are trimmed from the top of the panicking goroutine's stack trace
+// (and skipped over when working out Info.Function). Libraries wrapping sanepanic can use this to hide their own frames,
+// so the trace starts at their user's code.
+func (ph *Handler) SetStackSkip(skip int) {
+	ph.captureMu.Lock()
+	defer ph.captureMu.Unlock()
+	ph.capture.stackSkip = skip
+}
+
+// Sets whether the package's listener counts how many goroutines are in each state when a panic is captured.
+// See Handler.SetCaptureGoroutineStates.
+func SetCaptureGoroutineStates(capture bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.SetCaptureGoroutineStates(capture)
+}
+
+// Sets whether this handler counts how many goroutines are in each state (running, chan receive, select and so on)
+// when a panic is captured, see Info.GoroutineStates. This is handy for diagnosing panics that are related to
+// deadlocks or leaks. The counts come from the trace of every goroutine, so with this on the whole trace is captured
+// (and kept as the StackTrace) however large it is, rather than being cut off at 10000 bytes.
+func (ph *Handler) SetCaptureGoroutineStates(capture bool) {
+	ph.captureMu.Lock()
+	defer ph.captureMu.Unlock()
+	ph.capture.goroutineStates = capture
+}
+
+// Limits the length of stack traces captured by the package's listener. See Handler.SetMaxStackLength.
+func SetMaxStackLength(n int) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.SetMaxStackLength(n)
+}
+
+// Limits Info.StackTrace to n bytes, so deeply recursive panics don't produce enormous logs and crash reports. Longer
+// traces are cut at the end of the last frame that fits and finished with a "... (truncated, M more bytes)" line.
+// Zero, the default, means no limit.
+func (ph *Handler) SetMaxStackLength(n int) {
+	ph.captureMu.Lock()
+	defer ph.captureMu.Unlock()
+	ph.capture.maxStackLength = n
+}
+
+// The same as Forward, except skip additional frames are trimmed from the top of the stack trace, for use in
+// wrappers that want to hide their own frames. Call it as "defer sanepanic.ForwardSkip(1)".
+func ForwardSkip(skip int) {
+	err := recover() // Have to do recover directly in deferred function
+	packageHandler().forwardSkip(err, skip)
+}
+
+// As with the package level function, "defer YourPanicHandler.ForwardSkip(skip)" forwards the panic to this handler
+// with skip additional frames trimmed from the stack trace.
+func (ph *Handler) ForwardSkip(skip int) {
+	err := recover()
+	ph.forwardSkip(err, skip)
+}
+
+// A StackTracer is a panic value that carries the stack trace of wherever it originally came from. When one is
+// forwarded its stack trace is used as is, rather than capturing the stack of the goroutine forwarding it.
+type StackTracer interface {
+	StackTrace() string
+}
+
+// Captures the Info for a panic, trimming skip frames (on top of the handler's own setting) from the top of the stack.
+func (ph *Handler) newInfo(err interface{}, skip int) Info {
+	opts := ph.captureOptions()
+	opts.stackSkip += skip
+	return captureInfo(err, opts)
+}
+
+func captureInfo(err interface{}, opts captureOptions) Info {
+	// Something that's already been through sanepanic is being forwarded again, keep its origin
+	switch original := err.(type) {
+	case Info:
+		return original
+	case *Info:
+		if original != nil {
+			return *original
+		}
+	case *RepanicError:
+		if original != nil {
+			return original.info
+		}
+	}
+
+	base := goroutineBaseline()
+	buf := allStacks(opts.goroutineStates || base != nil) // Counting goroutines needs the whole trace
+	function := panickingFunction(opts.stackSkip)
+	info := buildInfo(err, buf, opts)
+	info.StackTrace = trimStack(string(buf), function)
+	info.Function = function
+	if base != nil {
+		info.NewGoroutines = newGoroutines(buf, base)
+	}
+	if tracer, ok := err.(StackTracer); ok {
+		// Describe where the value came from rather than where it was forwarded
+		info.StackTrace = tracer.StackTrace()
+		info.Function = stackFunction(info.StackTrace)
+		if id := goroutineID([]byte(info.StackTrace)); id != 0 {
+			info.GoroutineID, info.GoroutineName = id, goroutineName(id)
+		}
+	}
+	info.StackTrace = truncateStack(info.StackTrace, opts.maxStackLength)
+	return info
+}
+
+// Returns the trace of every goroutine from runtime.Stack. Unless whole is set it's cut off at 10000 bytes.
+func allStacks(whole bool) []byte {
+	buf := make([]byte, 10000)
+	traceSize := runtime.Stack(buf, true)
+	for whole && traceSize == len(buf) {
+		buf = make([]byte, 2*len(buf))
+		traceSize = runtime.Stack(buf, true)
+	}
+	return buf[:traceSize]
+}
+
+// Builds the Info for a panic whose stack was captured somewhere else, e.g. by debug.Stack in another recovery point.
+// The stack is kept verbatim.
+func captureInfoWithStack(err interface{}, stack string, opts captureOptions) Info {
+	info := buildInfo(err, []byte(stack), opts)
+	info.StackTrace = truncateStack(stack, opts.maxStackLength)
+	info.Function = stackFunction(stack)
+	return info
+}
+
+// A snapshot of the runtime settings that matter for performance related crashes such as scheduler starvation.
+// Capturing them is cheap (no stop the world), so it's done for every panic.
+type RuntimeInfo struct {
+	NumCPU     int   // From runtime.NumCPU
+	GOMAXPROCS int   // From runtime.GOMAXPROCS(0)
+	NumCgoCall int64 // From runtime.NumCgoCall, the number of cgo calls the process has made so far
+}
+
+func captureRuntimeInfo() RuntimeInfo {
+	return RuntimeInfo{
+		NumCPU:     runtime.NumCPU(),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+		NumCgoCall: runtime.NumCgoCall(),
+	}
+}
+
+// Fills in everything but the StackTrace and Function, which depend on where the stack came from.
+func buildInfo(err interface{}, buf []byte, opts captureOptions) Info {
+	info := Info{
+		Info:        err,
+		Time:        time.Now(),
+		GoroutineID: goroutineID(buf),
+		Runtime:     captureRuntimeInfo(),
+	}
+	info.GoroutineName = goroutineName(info.GoroutineID)
+	if opts.goroutineStates {
+		states := goroutineStates(buf)
+		info.states = &states
+	}
+	if sink := currentLogSink(); sink != nil {
+		logs := sink.recent()
+		info.logs = &logs
+	}
+	if _, ok := err.(runtime.Error); ok {
+		info.IsRuntimeError = true
+	}
+	return info
+}
+
+// Turns nop mode on or off for the package's listener. See Handler.SetNopMode.
+func SetNopMode(nop bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.SetNopMode(nop)
+}
+
+// In nop mode panics are recovered and then silently swallowed, without capturing a stack trace or sending anything
+// to the listener, so the HandlerFunc never sees them. This is much cheaper than installing NopHandlerFunc, which still
+// pays for both, and is meant for benchmarks and tests that panic a lot on purpose. Panics forwarded while nop mode
+// is on are lost, turning it off again only affects future ones.
+func (ph *Handler) SetNopMode(nop bool) {
+	var flag int32
+	if nop {
+		flag = 1
+	}
+	atomic.StoreInt32(&ph.nop, flag)
+}
+
+func (ph *Handler) nopMode() bool {
+	return atomic.LoadInt32(&ph.nop) != 0
+}
+
+// Cuts stack down to at most max bytes (plus the marker saying so), without splitting a frame.
+func truncateStack(stack string, max int) string {
+	if max <= 0 || len(stack) <= max {
+		return stack
+	}
+	// Each frame is a function line followed by a tab indented location line, so back up until the next line isn't a location
+	end := strings.LastIndexByte(stack[:max], '\n')
+	for end >= 0 && end+1 < len(stack) && stack[end+1] == '\t' {
+		end = strings.LastIndexByte(stack[:end], '\n')
+	}
+	kept := stack[:end+1]
+	return fmt.Sprintf("%s... (truncated, %d more bytes)\n", kept, len(stack)-len(kept))
+}
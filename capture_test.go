@@ -0,0 +1,256 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A two level wrapper, as a library built on sanepanic might have
+func check(cond bool) {
+	if !cond {
+		fail()
+	}
+}
+
+func fail() {
+	panic("Check failed")
+}
+
+func userCode() {
+	check(false)
+}
+
+func firstFrame(stack string) string {
+	lines := strings.SplitN(stack, "\n", 3)
+	if len(lines) < 2 {
+		return ""
+	}
+	return lines[1]
+}
+
+func TestStackTrimming(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	go func() {
+		defer ph.Forward()
+		userCode()
+	}()
+
+	info := <-handled
+	if !strings.HasSuffix(info.Function, ".fail") {
+		t.Errorf("Expected the panic to originate in fail, got %q", info.Function)
+	}
+	if !strings.HasPrefix(firstFrame(info.StackTrace), info.Function+"(") {
+		t.Errorf("Expected the stack trace to start at %s:\n%s", info.Function, info.StackTrace)
+	}
+}
+
+func TestForwardSkip(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	go func() {
+		defer ph.ForwardSkip(2)
+		userCode()
+	}()
+
+	info := <-handled
+	if !strings.HasSuffix(info.Function, ".userCode") {
+		t.Errorf("Expected the panic to originate in userCode, got %q", info.Function)
+	}
+	if !strings.HasPrefix(firstFrame(info.StackTrace), info.Function+"(") {
+		t.Errorf("Expected the stack trace to start at %s:\n%s", info.Function, info.StackTrace)
+	}
+}
+
+func TestSetStackSkip(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+	ph.SetStackSkip(1)
+
+	go func() {
+		defer ph.ForwardSkip(1)
+		userCode()
+	}()
+
+	if info := <-handled; !strings.HasSuffix(info.Function, ".userCode") {
+		t.Errorf("Expected the panic to originate in userCode, got %q", info.Function)
+	}
+}
+
+func TestIsRuntimeError(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	go func() {
+		defer ph.Forward()
+		s := []int{}
+		i := 3
+		_ = s[i]
+	}()
+	if info := <-handled; !info.IsRuntimeError {
+		t.Errorf("Index out of range panic %v wasn't flagged as a runtime error", info.Info)
+	}
+
+	go func() {
+		defer ph.Forward()
+		panic("custom")
+	}()
+	if info := <-handled; info.IsRuntimeError {
+		t.Errorf("Custom panic %v was flagged as a runtime error", info.Info)
+	}
+}
+
+func TestForwardValueWithStack(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	stack := "goroutine 7 [running]:\n" +
+		"main.bridge.func1()\n\t/src/main.go:10 +0x1d\n" +
+		"panic({0x4b2a40?, 0x5208b0?})\n\t/usr/local/go/src/runtime/panic.go:785 +0x132\n" +
+		"main.doWork()\n\t/src/main.go:20 +0x25\n"
+	ph.ForwardValueWithStack("Oh no!", stack)
+
+	info := <-handled
+	if info.StackTrace != stack {
+		t.Errorf("Stack trace was changed, got:\n%s", info.StackTrace)
+	}
+	if info.GoroutineID != 7 {
+		t.Errorf("Expected goroutine 7 from the given stack, got %d", info.GoroutineID)
+	}
+	if info.Function != "main.doWork" {
+		t.Errorf("Expected the function below the panic, got %q", info.Function)
+	}
+
+	ph.ForwardValueWithStack("Oh no!", "")
+	info = <-handled
+	if !strings.Contains(info.StackTrace, "TestForwardValueWithStack") {
+		t.Errorf("Expected a stack to be captured when none was given, got:\n%s", info.StackTrace)
+	}
+}
+
+func TestNopMode(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+	ph.SetNopMode(true)
+
+	func() {
+		defer ph.Forward()
+		panic("Swallowed")
+	}()
+	ph.ForwardValueSync("Also swallowed")
+
+	ph.SetNopMode(false)
+	ph.ForwardValue("Handled")
+	if info := <-handled; info.Info != "Handled" {
+		t.Errorf("A panic forwarded in nop mode reached the HandlerFunc: %v", info.Info)
+	}
+}
+
+func benchmarkForward(b *testing.B, nop bool) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+	ph.SetNopMode(nop)
+
+	for i := 0; i < b.N; i++ {
+		func() {
+			defer ph.Forward()
+			panic("Oh no!")
+		}()
+	}
+	ph.Drain(time.Minute)
+}
+
+func BenchmarkForward(b *testing.B) {
+	benchmarkForward(b, false)
+}
+
+func BenchmarkForwardNopMode(b *testing.B) {
+	benchmarkForward(b, true)
+}
+
+func recurse(n int) {
+	if n == 0 {
+		panic("Too deep")
+	}
+	recurse(n - 1)
+}
+
+func TestSetMaxStackLength(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+	ph.SetMaxStackLength(1000)
+
+	go func() {
+		defer ph.Forward()
+		recurse(100)
+	}()
+
+	trace := (<-handled).StackTrace
+	marker := strings.LastIndex(trace, "... (truncated, ")
+	if marker < 0 || !strings.HasSuffix(trace, " more bytes)\n") {
+		t.Fatalf("Expected the trace to end with a truncation marker:\n%s", trace)
+	}
+	if marker > 1000 {
+		t.Errorf("Expected at most 1000 bytes of trace before the marker, got %d", marker)
+	}
+	kept := strings.Split(trace[:marker], "\n")
+	if last := kept[len(kept)-2]; !strings.HasPrefix(last, "\t") || kept[len(kept)-1] != "" {
+		t.Errorf("Expected the trace to be cut at the end of a frame:\n%s", trace)
+	}
+}
+
+func TestRuntimeInfo(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(3))
+	ph.ForwardValue("Oh no!")
+
+	info := <-handled
+	if info.Runtime.NumCPU != runtime.NumCPU() {
+		t.Errorf("Expected NumCPU %d, got %d", runtime.NumCPU(), info.Runtime.NumCPU)
+	}
+	if info.Runtime.GOMAXPROCS != 3 {
+		t.Errorf("Expected GOMAXPROCS 3 at the time of the panic, got %d", info.Runtime.GOMAXPROCS)
+	}
+	if info.Runtime.NumCgoCall < 0 {
+		t.Errorf("Implausible NumCgoCall %d", info.Runtime.NumCgoCall)
+	}
+}
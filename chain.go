@@ -0,0 +1,55 @@
+package sanepanic
+
+import (
+	"sort"
+)
+
+type chainedHandlerFunc struct {
+	handle   HandlerFunc
+	priority int
+}
+
+// Registers an additional HandlerFunc with the package's listener. See Handler.AddHandlerFuncWithPriority.
+func AddHandlerFunc(handler HandlerFunc) {
+	AddHandlerFuncWithPriority(handler, 0)
+}
+
+// Registers an additional HandlerFunc with the package's listener. See Handler.AddHandlerFuncWithPriority.
+func AddHandlerFuncWithPriority(handler HandlerFunc, priority int) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.AddHandlerFuncWithPriority(handler, priority)
+}
+
+// Registers an additional HandlerFunc with a priority of 0.
+func (ph *Handler) AddHandlerFunc(handler HandlerFunc) {
+	ph.AddHandlerFuncWithPriority(handler, 0)
+}
+
+// Registers an additional HandlerFunc to run on every panic. Registered HandlerFuncs run from the highest priority to the
+// lowest (those with equal priorities run in the order they were added), followed by the HandlerFunc set with
+// SetHandlerFunc. This lets independent modules add their handling without coordinating who registers first.
+//
+// If any of them returns false the rest are skipped for that panic and the listener stops.
+//
+// The HandlerFuncs for a panic run one after another on the listener's goroutine, so each one happens before the next:
+// anything written by an earlier HandlerFunc is visible to the later ones without further synchronization. The same goes
+// for consecutive panics, which are never handled concurrently.
+func (ph *Handler) AddHandlerFuncWithPriority(handler HandlerFunc, priority int) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.chain = append(ph.chain, chainedHandlerFunc{handle: handler, priority: priority})
+	sort.SliceStable(ph.chain, func(i, j int) bool {
+		return ph.chain[i].priority > ph.chain[j].priority
+	})
+}
+
+// Runs the registered HandlerFuncs followed by the main one, in order on the calling goroutine. Expects ph.mu to be held.
+func (ph *Handler) runHandlerFuncs(info Info) bool {
+	for _, chained := range ph.chain {
+		if !chained.handle(info) {
+			return false
+		}
+	}
+	return ph.handle(info)
+}
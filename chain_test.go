@@ -0,0 +1,107 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestHandlerFuncPriority(t *testing.T) {
+	order := []string{}
+	record := func(name string) sanepanic.HandlerFunc {
+		return func(sanepanic.Info) bool {
+			order = append(order, name)
+			return true
+		}
+	}
+
+	ph := sanepanic.NewHandler(record("main"))
+	defer ph.Done()
+
+	ph.AddHandlerFuncWithPriority(record("low"), -1)
+	ph.AddHandlerFunc(record("default"))
+	ph.AddHandlerFuncWithPriority(record("high"), 10)
+	ph.AddHandlerFuncWithPriority(record("medium 1"), 5)
+	ph.AddHandlerFuncWithPriority(record("medium 2"), 5)
+
+	ph.ForwardValueSync("Oh no!")
+
+	expected := []string{"high", "medium 1", "medium 2", "default", "low", "main"}
+	if !reflect.DeepEqual(order, expected) {
+		t.Errorf("Handlers ran in order %v, expected %v", order, expected)
+	}
+}
+
+func TestHandlerFuncChainStops(t *testing.T) {
+	ranMain := false
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		ranMain = true
+		return true
+	})
+	defer ph.Done()
+
+	ph.AddHandlerFunc(func(sanepanic.Info) bool {
+		return false
+	})
+
+	ph.ForwardValueSync("Oh no!")
+
+	if ranMain {
+		t.Errorf("The main handler ran after a registered handler returned false")
+	}
+}
+
+// Deliberately unsynchronized, so the race detector catches any handler running out of order or concurrently
+type crashState struct {
+	persisted  []interface{}
+	metrics    []interface{}
+	notified   []interface{}
+	violations int
+}
+
+func TestHandlerFuncOrdering(t *testing.T) {
+	const panics = 20
+
+	state := &crashState{}
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		// Notify, which must see both earlier steps for this panic
+		if len(state.metrics) != len(state.notified)+1 || state.metrics[len(state.metrics)-1] != info.Info {
+			state.violations++
+		}
+		state.notified = append(state.notified, info.Info)
+		return true
+	})
+	defer ph.Done()
+
+	ph.AddHandlerFuncWithPriority(func(info sanepanic.Info) bool {
+		// Metrics, registered first but must run after persisting
+		if len(state.persisted) != len(state.metrics)+1 || state.persisted[len(state.persisted)-1] != info.Info {
+			state.violations++
+		}
+		state.metrics = append(state.metrics, info.Info)
+		return true
+	}, 0)
+	ph.AddHandlerFuncWithPriority(func(info sanepanic.Info) bool {
+		state.persisted = append(state.persisted, info.Info)
+		return true
+	}, 10)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(panics)
+	for i := 0; i < panics; i++ {
+		go func(i int) {
+			defer ph.ForwardAndDone(wg)
+			panic(i)
+		}(i)
+	}
+	wg.Wait()
+	ph.Done() // Handles anything still in flight before returning
+
+	if state.violations != 0 {
+		t.Errorf("Handlers observed %d ordering violations", state.violations)
+	}
+	if len(state.notified) != panics {
+		t.Errorf("Expected %d panics to make it through every handler, %d did", panics, len(state.notified))
+	}
+}
@@ -0,0 +1,65 @@
+package sanepanic
+
+import (
+	"time"
+)
+
+// Watches the rate of panics and trips once it's too high.
+type circuitBreaker struct {
+	threshold int
+	window    time.Duration
+	onOpen    func()
+	recent    []time.Time // When the panics in the current window were handled, oldest first
+	open      bool
+}
+
+// Records a panic, tripping the breaker if that's more than threshold in the window.
+func (cb *circuitBreaker) record(now time.Time) {
+	if cb.threshold <= 0 || cb.open {
+		return
+	}
+	cb.recent = append(cb.recent, now)
+	for len(cb.recent) > 0 && now.Sub(cb.recent[0]) > cb.window {
+		cb.recent = cb.recent[1:]
+	}
+	if len(cb.recent) > cb.threshold {
+		cb.open, cb.recent = true, nil
+		if cb.onOpen != nil {
+			cb.onOpen()
+		}
+	}
+}
+
+// Installs a circuit breaker on the package's listener. See Handler.SetCircuitBreaker.
+func SetCircuitBreaker(threshold int, window time.Duration, onOpen func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.SetCircuitBreaker(threshold, window, onOpen)
+}
+
+// Installs a circuit breaker that opens if more than threshold panics are handled within window, on the theory that
+// a process panicking that often is beyond saving. When it opens onOpen is called, once, from the listener before
+// the HandlerFunc sees the panic that tripped it; this is the place for something drastic like exiting the process.
+// Panics are still handled as usual while the breaker is open, see CircuitOpen.
+//
+// The breaker stays open until SetCircuitBreaker is called again, which starts a fresh, closed one.
+// A threshold of zero or less removes the breaker.
+func (ph *Handler) SetCircuitBreaker(threshold int, window time.Duration, onOpen func()) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.breaker = circuitBreaker{threshold: threshold, window: window, onOpen: onOpen}
+}
+
+// Returns whether the package listener's circuit breaker has opened.
+func CircuitOpen() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return internalPanicHandler.CircuitOpen()
+}
+
+// Returns whether this handler's circuit breaker has opened, see SetCircuitBreaker.
+func (ph *Handler) CircuitOpen() bool {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	return ph.breaker.open
+}
@@ -0,0 +1,55 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	handled := 0
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		handled++
+		return true
+	})
+	defer ph.Done()
+
+	opened := 0
+	ph.SetCircuitBreaker(3, time.Minute, func() {
+		opened++
+	})
+
+	for i := 0; i < 3; i++ {
+		ph.ForwardValueSync("Oh no!")
+	}
+	if opened != 0 || ph.CircuitOpen() {
+		t.Fatalf("Circuit breaker opened without exceeding the threshold")
+	}
+
+	for i := 0; i < 5; i++ {
+		ph.ForwardValueSync("Oh no!")
+	}
+	if opened != 1 {
+		t.Errorf("Expected onOpen to be called once, it was called %d times", opened)
+	}
+	if !ph.CircuitOpen() {
+		t.Errorf("Circuit breaker isn't open")
+	}
+	if handled != 8 {
+		t.Errorf("Expected every panic to be handled with the circuit open, %d were", handled)
+	}
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+
+	ph.SetCircuitBreaker(1, 20*time.Millisecond, func() {
+		t.Errorf("Circuit breaker opened even though the panics were further apart than the window")
+	})
+
+	for i := 0; i < 3; i++ {
+		ph.ForwardValueSync("Oh no!")
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,104 @@
+package sanepanic
+
+import (
+	"context"
+	"time"
+)
+
+// A HandlerFuncCtx is a HandlerFunc that also receives the context the panic was forwarded with,
+// so that handlers doing I/O (writing a dump, calling a remote reporter) can respect its deadline.
+type HandlerFuncCtx func(context.Context, Info) (keepHandling bool)
+
+// Adapts a HandlerFuncCtx so it can be installed like any other HandlerFunc. Panics that weren't forwarded
+// with a context are handled with context.Background().
+func ContextHandlerFunc(handler HandlerFuncCtx) HandlerFunc {
+	return func(info Info) bool {
+		ctx := info.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		return handler(ctx, info)
+	}
+}
+
+// The same as Forward, except ctx is carried along on the Info for the HandlerFunc to use.
+// As with Forward, call it as "defer sanepanic.ForwardWithContext(ctx)".
+func ForwardWithContext(ctx context.Context) {
+	err := recover() // Have to do recover directly in deferred function
+	packageHandler().forwardWithContext(ctx, err)
+}
+
+// As with the package level function, "defer YourPanicHandler.ForwardWithContext(ctx)" forwards the panic to this handler
+// with ctx carried along on the Info.
+func (ph *Handler) ForwardWithContext(ctx context.Context) {
+	err := recover()
+	ph.forwardWithContext(ctx, err)
+}
+
+func (ph *Handler) forwardWithContext(ctx context.Context, err interface{}) {
+	if err != nil && !ph.nopMode() {
+		info := ph.newInfo(err, 0)
+		info.Context = ctx
+		ph.send(forwardedPanic{info: info})
+	}
+}
+
+// The same as Forward, except deadline is attached to the Info, e.g. the deadline of the request the goroutine was
+// serving. As with Forward, call it as "defer sanepanic.ForwardWithDeadline(deadline)".
+func ForwardWithDeadline(deadline time.Time) {
+	err := recover() // Have to do recover directly in deferred function
+	packageHandler().forwardWithDeadline(deadline, err)
+}
+
+// As with the package level function, "defer YourPanicHandler.ForwardWithDeadline(deadline)" forwards the panic to
+// this handler with deadline attached to the Info.
+func (ph *Handler) ForwardWithDeadline(deadline time.Time) {
+	err := recover()
+	ph.forwardWithDeadline(deadline, err)
+}
+
+func (ph *Handler) forwardWithDeadline(deadline time.Time, err interface{}) {
+	if err != nil && !ph.nopMode() {
+		info := ph.newInfo(err, 0)
+		info.Deadline = deadline
+		ph.send(forwardedPanic{info: info})
+	}
+}
+
+type fieldsKey struct{}
+
+// Returns a copy of ctx carrying fields, added to any fields ctx already carries (fields replace existing ones with the
+// same key). When a panic is forwarded with ForwardWithContext these show up in Info.Labels, so middleware can attach
+// request-scoped data once and have it appear on any panic further down.
+//
+// Go has no goroutine-local storage, so this only works where the context is passed to ForwardWithContext; a panic
+// forwarded with Forward can't see the fields of whatever context its goroutine happened to be using.
+func ContextWithFields(ctx context.Context, fields map[string]string) context.Context {
+	merged := map[string]string{}
+	if existing, ok := ctx.Value(fieldsKey{}).(map[string]string); ok {
+		for k, v := range existing {
+			merged[k] = v
+		}
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// Returns a copy of the fields attached with ContextWithFields to the context the panic was forwarded with,
+// or nil if there aren't any.
+func (info Info) Labels() map[string]string {
+	if info.Context == nil {
+		return nil
+	}
+	fields, ok := info.Context.Value(fieldsKey{}).(map[string]string)
+	if !ok {
+		return nil
+	}
+	labels := make(map[string]string, len(fields))
+	for k, v := range fields {
+		labels[k] = v
+	}
+	return labels
+}
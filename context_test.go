@@ -0,0 +1,99 @@
+package sanepanic_test
+
+import (
+	"context"
+	"github.com/Jragonmiris/sanepanic"
+	"testing"
+	"time"
+)
+
+func TestForwardWithContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	observed := make(chan context.Context, 1)
+	ph := sanepanic.NewHandler(sanepanic.ContextHandlerFunc(func(ctx context.Context, info sanepanic.Info) bool {
+		observed <- ctx
+		return true
+	}))
+	defer ph.Done()
+
+	go func() {
+		defer ph.ForwardWithContext(ctx)
+		panic("Oh no!")
+	}()
+
+	handlerCtx := <-observed
+	if handlerCtx != ctx {
+		t.Fatalf("Handler received a different context than the one forwarded")
+	}
+	if _, ok := handlerCtx.Deadline(); !ok {
+		t.Errorf("Handler's context lost its deadline")
+	}
+}
+
+func TestForwardWithDeadline(t *testing.T) {
+	deadline := time.Now().Add(time.Minute)
+
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	go func() {
+		defer ph.ForwardWithDeadline(deadline)
+		panic("Oh no!")
+	}()
+
+	info := <-handled
+	if !info.Deadline.Equal(deadline) {
+		t.Errorf("Expected deadline %v, got %v", deadline, info.Deadline)
+	}
+	if !info.Time.Before(info.Deadline) {
+		t.Errorf("Expected the panic at %v to be before the deadline", info.Time)
+	}
+}
+
+func TestContextHandlerFuncWithoutContext(t *testing.T) {
+	handler := sanepanic.ContextHandlerFunc(func(ctx context.Context, info sanepanic.Info) bool {
+		if ctx == nil {
+			t.Errorf("Handler received a nil context")
+		}
+		return true
+	})
+
+	handler(sanepanic.Info{Info: "Oh no!"})
+}
+
+func TestContextWithFields(t *testing.T) {
+	ctx := sanepanic.ContextWithFields(context.Background(), map[string]string{"request": "42", "user": "nobody"})
+	ctx = sanepanic.ContextWithFields(ctx, map[string]string{"user": "gopher"})
+
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	go func() {
+		defer ph.ForwardWithContext(ctx)
+		panic("Oh no!")
+	}()
+
+	labels := (<-handled).Labels()
+	if len(labels) != 2 || labels["request"] != "42" || labels["user"] != "gopher" {
+		t.Errorf("Expected the context's fields as labels, got %v", labels)
+	}
+}
+
+func TestLabelsWithoutFields(t *testing.T) {
+	if labels := (sanepanic.Info{Info: "Oh no!"}).Labels(); labels != nil {
+		t.Errorf("Expected no labels without a context, got %v", labels)
+	}
+	if labels := (sanepanic.Info{Info: "Oh no!", Context: context.Background()}).Labels(); labels != nil {
+		t.Errorf("Expected no labels without fields, got %v", labels)
+	}
+}
@@ -0,0 +1,130 @@
+package sanepanic
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Waits up to timeout for every panic forwarded to the package's listener to be handled. See Handler.Drain.
+func Drain(timeout time.Duration) bool {
+	return DrainWithCallback(timeout, nil)
+}
+
+// Waits up to timeout for every panic forwarded to the package's listener to be handled, passing any that aren't
+// to onDropped. See Handler.DrainWithCallback.
+func DrainWithCallback(timeout time.Duration, onDropped func(Info)) bool {
+	ph := packageHandler()
+	return ph.DrainWithCallback(timeout, onDropped)
+}
+
+// Waits up to timeout for every panic that's been forwarded to this handler to be handled, and returns true if they all were.
+// Any panics still waiting once the timeout expires are discarded. Panics forwarded after Drain returns are handled as
+// usual, but this is meant for shutting down, so it's usually followed by Done.
+func (ph *Handler) Drain(timeout time.Duration) bool {
+	return ph.DrainWithCallback(timeout, nil)
+}
+
+// The same as Drain, except panics that are discarded because they missed the timeout are passed to onDropped instead,
+// giving you a last chance to at least log them. The HandlerFunc currently running (if any) is left to finish, and the
+// Info passed to onDropped hasn't been through the redactor.
+func (ph *Handler) DrainWithCallback(timeout time.Duration, onDropped func(Info)) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	select {
+	case <-ph.whenIdle():
+		return true
+	case <-deadline.C:
+		ph.dropPending(onDropped)
+		return false
+	}
+}
+
+// Returns a channel that's closed once no panics are pending, which it already is if none are now.
+func (ph *Handler) whenIdle() <-chan struct{} {
+	ph.idleMu.Lock()
+	defer ph.idleMu.Unlock()
+	if atomic.LoadInt64(&ph.pending) == 0 {
+		idle := make(chan struct{})
+		close(idle)
+		return idle
+	}
+	if ph.idle == nil {
+		ph.idle = make(chan struct{})
+	}
+	return ph.idle
+}
+
+// Marks a panic as no longer pending, waking up anything waiting in whenIdle if it was the last.
+func (ph *Handler) unpend() {
+	if atomic.AddInt64(&ph.pending, -1) > 0 {
+		return
+	}
+	ph.idleMu.Lock()
+	defer ph.idleMu.Unlock()
+	// Something may have been forwarded since, in which case whoever settles it wakes the waiters instead
+	if ph.idle != nil && atomic.LoadInt64(&ph.pending) == 0 {
+		close(ph.idle)
+		ph.idle = nil
+	}
+}
+
+// Drops everything that's still waiting to be received, with the listener dropping rather than handling anything it
+// picks up in the meantime. Once the backlog is gone the listener goes back to handling panics as usual.
+func (ph *Handler) dropPending(onDropped func(Info)) {
+	dropMu := &sync.Mutex{} // Both the listener and the draining goroutine may drop panics
+	drop := func(info Info) {
+		if onDropped != nil {
+			dropMu.Lock()
+			defer dropMu.Unlock()
+			onDropped(info)
+		}
+	}
+	ph.dropper.Store(drop)
+	ph.lastDrop.Store(drop)
+	atomic.AddUint64(&ph.timeouts, 1) // After storing the dropper, see listenBatched
+
+	for _, fp := range ph.releaseAll() { // Held while paused
+		ph.dropIfDraining(fp)
+	}
+	defer ph.dropper.Store((func(Info))(nil))
+	for {
+		select {
+		case fp := <-ph.panicChan:
+			ph.dropIfDraining(fp)
+		default:
+			return
+		}
+	}
+}
+
+// Drops the batch if a drain has timed out since the batch was started (when ph.timeouts was started), as it was
+// still waiting to be handled then. Returns whether it did.
+func (ph *Handler) dropIfStale(batch []forwardedPanic, started uint64) bool {
+	if atomic.LoadUint64(&ph.timeouts) == started {
+		return false
+	}
+	drop := ph.lastDrop.Load().(func(Info))
+	for _, fp := range batch {
+		drop(fp.info)
+		ph.settle(fp)
+	}
+	return true
+}
+
+// Drops the panic if a drain has timed out, returns whether it did.
+func (ph *Handler) dropIfDraining(fp forwardedPanic) bool {
+	drop, _ := ph.dropper.Load().(func(Info))
+	if drop == nil {
+		return false
+	}
+	if fp.probe {
+		// Left unanswered, since the panic it stands in for wouldn't have been handled
+		ph.unpend()
+		return true
+	}
+	drop(fp.info)
+	ph.settle(fp)
+	return true
+}
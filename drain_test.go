@@ -0,0 +1,119 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDrain(t *testing.T) {
+	handled := 0
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		time.Sleep(10 * time.Millisecond)
+		handled++
+		return true
+	})
+	defer ph.Done()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			defer ph.ForwardAndDone(wg)
+			panic("Arghlbarg")
+		}()
+	}
+	wg.Wait()
+
+	if !ph.Drain(5 * time.Second) {
+		t.Fatalf("Drain timed out")
+	}
+	if handled != 3 {
+		t.Errorf("Expected all 3 panics to be handled after draining, %d were", handled)
+	}
+}
+
+func TestDrainWithCallback(t *testing.T) {
+	const total = 5
+
+	mu := &sync.Mutex{}
+	handled := map[interface{}]bool{}
+	started := make(chan struct{}, total)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		started <- struct{}{}
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		handled[info.Info] = true
+		return true
+	})
+	defer ph.Done()
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ph.ForwardValueSync(i)
+		}(i)
+	}
+	<-started
+	time.Sleep(20 * time.Millisecond) // Give the rest time to queue up behind the slow handler
+
+	dropped := map[interface{}]bool{}
+	if ph.DrainWithCallback(150*time.Millisecond, func(info sanepanic.Info) {
+		dropped[info.Info] = true
+	}) {
+		t.Errorf("Drain reported every panic was handled in time")
+	}
+	wg.Wait() // Every panic is either handled or dropped, so nobody is left waiting
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dropped) == 0 {
+		t.Errorf("No panics were dropped")
+	}
+	for i := 0; i < total; i++ {
+		if handled[i] == dropped[i] {
+			t.Errorf("Expected panic %d to be either handled or dropped (handled: %v, dropped: %v)", i, handled[i], dropped[i])
+		}
+	}
+}
+
+func TestHandlingAfterDrainTimeout(t *testing.T) {
+	handled := make(chan interface{}, 10)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		time.Sleep(50 * time.Millisecond)
+		handled <- info.Info
+		return true
+	})
+	defer ph.Done()
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ph.ForwardValueSync(i)
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if ph.Drain(time.Millisecond) {
+		t.Fatalf("Drain reported every panic was handled in time")
+	}
+	wg.Wait()
+	for len(handled) > 0 {
+		<-handled
+	}
+
+	ph.ForwardValue("After the drain")
+	select {
+	case v := <-handled:
+		if v != "After the drain" {
+			t.Errorf("Unexpected panic %v handled after the drain", v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("A panic forwarded after a timed out drain was never handled")
+	}
+}
@@ -0,0 +1,30 @@
+package sanepanic
+
+import (
+	"sync"
+)
+
+// Sets a function to run on the first panic the package's listener handles. See Handler.SetOnFirstPanic.
+func SetOnFirstPanic(fn func(Info)) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.SetOnFirstPanic(fn)
+}
+
+// Sets fn to be run, before the HandlerFunc, on the very first panic this handler handles. It never runs again, even
+// after Restart, and if a panic has already been handled it never runs at all. A handler made by Clone starts afresh
+// and runs fn on the first panic it handles itself. This is the place for expensive setup that's only needed once
+// something has gone wrong, like connecting to a crash reporting service or allocating an emergency buffer.
+func (ph *Handler) SetOnFirstPanic(fn func(Info)) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.onFirst = fn
+}
+
+// Expects ph.mu to be held.
+func (ph *Handler) firstPanicOnce() *sync.Once {
+	if ph.firstPanic == nil {
+		ph.firstPanic = &sync.Once{}
+	}
+	return ph.firstPanic
+}
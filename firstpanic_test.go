@@ -0,0 +1,55 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"testing"
+)
+
+func TestSetOnFirstPanic(t *testing.T) {
+	calls := []string{}
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		calls = append(calls, "handler")
+		return true
+	})
+	defer ph.Done()
+	ph.SetOnFirstPanic(func(info sanepanic.Info) {
+		calls = append(calls, "first "+info.Info.(string))
+	})
+
+	ph.ForwardValueSync("one")
+	ph.ForwardValueSync("two")
+	ph.ForwardValueSync("three")
+
+	clone := ph.Clone()
+	defer clone.Done()
+	clone.ForwardValueSync("four")
+
+	expected := []string{"first one", "handler", "handler", "handler", "first four", "handler"}
+	if len(calls) != len(expected) {
+		t.Fatalf("Expected calls %v, got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("Expected calls %v, got %v", expected, calls)
+		}
+	}
+}
+
+func TestSetOnFirstPanicRestart(t *testing.T) {
+	defer sanepanic.SaveGlobalState()()
+	sanepanic.Restart() // Earlier tests may have left the listener stopped
+	sanepanic.SetHandlerFunc(sanepanic.NopHandlerFunc)
+
+	firsts := 0
+	sanepanic.SetOnFirstPanic(func(info sanepanic.Info) {
+		firsts++
+	})
+	sanepanic.ForwardValueSync("one")
+	before := firsts // Earlier tests may already have used up the package's first panic
+
+	sanepanic.Restart()
+	sanepanic.ForwardValueSync("two")
+	if firsts != before {
+		t.Errorf("Expected the first panic hook not to run again after Restart")
+	}
+}
@@ -0,0 +1,32 @@
+package sanepanic
+
+// A Flusher is anything with buffered output that should be written out before a panic is handled,
+// such as a bufio.Writer behind your logger.
+type Flusher interface {
+	Flush() error
+}
+
+// Registers a Flusher to be flushed before the package's listener handles each panic. See Handler.RegisterFlusher.
+func RegisterFlusher(f Flusher) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.RegisterFlusher(f)
+}
+
+// Registers a Flusher to be flushed before this handler runs its HandlerFuncs for each panic, so that buffered logs
+// leading up to the panic are written out before the crash report. Flush errors are logged rather than stopping
+// the panic from being handled.
+func (ph *Handler) RegisterFlusher(f Flusher) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.flushers = append(ph.flushers, f)
+}
+
+// Expects ph.mu to be held.
+func (ph *Handler) flush() {
+	for _, f := range ph.flushers {
+		if err := f.Flush(); err != nil {
+			logf("could not flush before handling a panic: %v", err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package sanepanic_test
+
+import (
+	"bytes"
+	"errors"
+	"github.com/Jragonmiris/sanepanic"
+	"os"
+	"strings"
+	"testing"
+)
+
+type fakeFlusher struct {
+	flushes int
+	err     error
+}
+
+func (f *fakeFlusher) Flush() error {
+	f.flushes++
+	return f.err
+}
+
+func TestRegisterFlusher(t *testing.T) {
+	flusher := &fakeFlusher{}
+	flushedFirst := true
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		if flusher.flushes == 0 {
+			flushedFirst = false
+		}
+		return true
+	})
+	defer ph.Done()
+	ph.RegisterFlusher(flusher)
+
+	ph.ForwardValueSync("Oh no!")
+	if !flushedFirst {
+		t.Errorf("Handler ran before the flusher was flushed")
+	}
+	if flusher.flushes != 1 {
+		t.Errorf("Expected one flush, got %d", flusher.flushes)
+	}
+
+	ph.ForwardValueSync("Oh no!")
+	if flusher.flushes != 2 {
+		t.Errorf("Expected one flush per panic, got %d after 2 panics", flusher.flushes)
+	}
+}
+
+func TestFlusherError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	defer sanepanic.SetOutput(os.Stderr)
+
+	handled := false
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		handled = true
+		return true
+	})
+	defer ph.Done()
+	ph.RegisterFlusher(&fakeFlusher{err: errors.New("disk full")})
+
+	ph.ForwardValueSync("Oh no!")
+	if !handled {
+		t.Errorf("A failed flush stopped the panic from being handled")
+	}
+	if !strings.Contains(buf.String(), "disk full") {
+		t.Errorf("Flush error wasn't logged, output was:\n%s", buf)
+	}
+}
@@ -0,0 +1,88 @@
+package sanepanic
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatInfo renders an Info as a multi-line report in roughly the same shape the runtime uses
+// when a program dies from a panic, prefixed with the time of the panic and the id of the goroutine it came from.
+// The output is suitable for writing straight to a crash file, and only depends on the fields of the Info
+// so it is stable for a given Info.
+func FormatInfo(info Info) string {
+	return formatInfo(info, false)
+}
+
+const (
+	ansiRed   = "\x1b[1;31m"
+	ansiDim   = "\x1b[2m"
+	ansiReset = "\x1b[0m"
+)
+
+// Formats the Info as FormatInfo does, optionally with a red header and dimmed stack trace for terminals.
+func formatInfo(info Info, color bool) string {
+	buf := &bytes.Buffer{}
+	if color {
+		fmt.Fprintf(buf, "%spanic: %v%s\n", ansiRed, info.Info, ansiReset)
+	} else {
+		fmt.Fprintf(buf, "panic: %v\n", info.Info)
+	}
+	fmt.Fprintf(buf, "time: %s\n", info.Time.Format(time.RFC3339Nano))
+	if info.GoroutineName != "" {
+		fmt.Fprintf(buf, "goroutine: %d (%s)\n", info.GoroutineID, info.GoroutineName)
+	} else {
+		fmt.Fprintf(buf, "goroutine: %d\n", info.GoroutineID)
+	}
+	if trace := strings.TrimSpace(info.StackTrace); trace != "" {
+		if color {
+			fmt.Fprintf(buf, "\n%s%s%s\n", ansiDim, trace, ansiReset)
+		} else {
+			fmt.Fprintf(buf, "\n%s\n", trace)
+		}
+	}
+	return buf.String()
+}
+
+// Returns up to n frames from the first goroutine in a stack trace, each as "function file:line".
+func stackFrames(trace string, n int) []string {
+	lines := strings.Split(trace, "\n")
+	frames := []string{}
+	for i := 1; i+1 < len(lines) && len(frames) < n; i += 2 { // Skip the "goroutine N" header
+		function, location := lines[i], strings.TrimSpace(lines[i+1])
+		if function == "" || !strings.HasPrefix(lines[i+1], "\t") {
+			break // End of the goroutine, or not a stack trace we understand
+		}
+		if paren := strings.LastIndex(function, "("); paren > 0 {
+			function = function[:paren]
+		}
+		if offset := strings.LastIndex(location, " +0x"); offset > 0 {
+			location = location[:offset]
+		}
+		frames = append(frames, function+" "+location)
+	}
+	return frames
+}
+
+// Returns the id of the calling goroutine.
+func currentGoroutineID() uint64 {
+	buf := make([]byte, 64)
+	return goroutineID(buf[:runtime.Stack(buf, false)])
+}
+
+// Pulls the id out of the "goroutine N [status]:" header runtime.Stack starts its output with.
+// The first goroutine listed is always the one that called runtime.Stack.
+func goroutineID(stack []byte) uint64 {
+	stack = bytes.TrimPrefix(stack, []byte("goroutine "))
+	if i := bytes.IndexByte(stack, ' '); i >= 0 {
+		stack = stack[:i]
+	}
+	id, err := strconv.ParseUint(string(stack), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
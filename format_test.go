@@ -0,0 +1,51 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatInfo(t *testing.T) {
+	info := sanepanic.Info{
+		Info:        "Oh no!",
+		StackTrace:  "goroutine 7 [running]:\nmain.main()\n\t/tmp/main.go:5 +0x20\n\n",
+		Time:        time.Date(2014, time.March, 1, 12, 30, 0, 0, time.UTC),
+		GoroutineID: 7,
+	}
+
+	expected := "panic: Oh no!\n" +
+		"time: 2014-03-01T12:30:00Z\n" +
+		"goroutine: 7\n" +
+		"\n" +
+		"goroutine 7 [running]:\nmain.main()\n\t/tmp/main.go:5 +0x20\n"
+
+	if formatted := sanepanic.FormatInfo(info); formatted != expected {
+		t.Errorf("Unexpected formatting, got:\n%s\nexpected:\n%s", formatted, expected)
+	}
+}
+
+func TestFormatForwardedInfo(t *testing.T) {
+	formatted := make(chan string, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		if info.GoroutineID == 0 {
+			t.Errorf("No goroutine id was captured")
+		}
+		formatted <- sanepanic.FormatInfo(info)
+		return false
+	})
+
+	go func() {
+		defer ph.Forward()
+		panic("Arghlbarg")
+	}()
+
+	report := <-formatted
+	if !strings.Contains(report, "panic: Arghlbarg\n") {
+		t.Errorf("Report does not contain the panic value:\n%s", report)
+	}
+	if !strings.Contains(report, "TestFormatForwardedInfo") {
+		t.Errorf("Report does not contain the stack trace:\n%s", report)
+	}
+}
@@ -0,0 +1,120 @@
+package sanepanic
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Names of goroutines started with GoNamed, keyed by goroutine id.
+var goroutineNames sync.Map
+
+func goroutineName(id uint64) string {
+	name, _ := goroutineNames.Load(id)
+	s, _ := name.(string)
+	return s
+}
+
+// Runs f in a new goroutine named name, with any panic forwarded to the package's listener. Go's goroutines are
+// anonymous, so the name is attached to the panic's Info as GoroutineName to make it easier to tell where it came from.
+func GoNamed(name string, f func()) {
+	go func() {
+		defer nameGoroutine(name)()
+		defer Forward()
+		f()
+	}()
+}
+
+// As with the package level function, runs f in a new goroutine named name, forwarding any panic to this handler.
+func (ph *Handler) GoNamed(name string, f func()) {
+	go func() {
+		defer nameGoroutine(name)()
+		defer ph.Forward()
+		f()
+	}()
+}
+
+// Names the calling goroutine, and returns a function that forgets the name again once it's finished.
+func nameGoroutine(name string) func() {
+	id := currentGoroutineID()
+	goroutineNames.Store(id, name)
+	return func() {
+		goroutineNames.Delete(id)
+	}
+}
+
+// Counts the goroutines in a runtime.Stack trace by state, taken from their "goroutine N [state]:" headers.
+// Anything after a comma in the state (e.g. how long it's been blocked) is ignored.
+func goroutineStates(stack []byte) map[string]int {
+	states := map[string]int{}
+	for _, line := range bytes.Split(stack, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("goroutine ")) {
+			continue
+		}
+		start, end := bytes.IndexByte(line, '['), bytes.LastIndexByte(line, ']')
+		if start < 0 || end < start {
+			continue
+		}
+		state := string(line[start+1 : end])
+		if comma := strings.IndexByte(state, ','); comma >= 0 {
+			state = state[:comma]
+		}
+		states[state]++
+	}
+	return states
+}
+
+// Returns how many goroutines were in each state (e.g. "running", "chan receive", "select") when the panic was
+// captured, or nil if the handler wasn't set to capture them with SetCaptureGoroutineStates.
+func (info Info) GoroutineStates() map[string]int {
+	if info.states == nil {
+		return nil
+	}
+	states := make(map[string]int, len(*info.states))
+	for state, count := range *info.states {
+		states[state] = count
+	}
+	return states
+}
+
+// The ids of the goroutines running when CaptureBaseline was called, a map[uint64]bool.
+var baseline atomic.Value
+
+// Records which goroutines are running now, typically at the end of startup once the long-lived ones have started.
+// From then on every panic captured also counts the goroutines that have appeared since, see Info.NewGoroutines.
+// Calling it again replaces the baseline. Note that with a baseline every capture includes the trace of every
+// goroutine however long it is, which gets expensive in processes with a great many of them.
+func CaptureBaseline() {
+	baseline.Store(goroutineIDs(allStacks(true)))
+}
+
+func goroutineBaseline() map[uint64]bool {
+	base, _ := baseline.Load().(map[uint64]bool)
+	return base
+}
+
+// Collects the ids from the "goroutine N [state]:" headers in a runtime.Stack trace.
+func goroutineIDs(stack []byte) map[uint64]bool {
+	ids := map[uint64]bool{}
+	for _, line := range bytes.Split(stack, []byte("\n")) {
+		if !bytes.HasPrefix(line, []byte("goroutine ")) {
+			continue
+		}
+		if id := goroutineID(line); id != 0 {
+			ids[id] = true
+		}
+	}
+	return ids
+}
+
+// Counts the goroutines in a runtime.Stack trace that aren't in base.
+func newGoroutines(stack []byte, base map[uint64]bool) int {
+	count := 0
+	for id := range goroutineIDs(stack) {
+		if !base[id] {
+			count++
+		}
+	}
+	return count
+}
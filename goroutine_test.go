@@ -0,0 +1,122 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGoNamed(t *testing.T) {
+	handled := make(chan sanepanic.Info, 2)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	ph.GoNamed("reader", func() {
+		panic("reader")
+	})
+	ph.GoNamed("writer", func() {
+		panic("writer")
+	})
+
+	for i := 0; i < 2; i++ {
+		info := <-handled
+		if info.GoroutineName != info.Info {
+			t.Errorf("Panic from the %v goroutine was reported with the name %q", info.Info, info.GoroutineName)
+		}
+		if !strings.Contains(sanepanic.FormatInfo(info), "("+info.GoroutineName+")") {
+			t.Errorf("Formatted panic is missing the goroutine name:\n%s", sanepanic.FormatInfo(info))
+		}
+	}
+}
+
+func TestUnnamedGoroutine(t *testing.T) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+	ph.SetHistorySize(1)
+
+	ph.ForwardValueSync("Oh no!")
+	if name := ph.RecentPanics()[0].GoroutineName; name != "" {
+		t.Errorf("Unnamed goroutine was reported with the name %q", name)
+	}
+}
+
+func TestGoroutineStates(t *testing.T) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+	ph.SetHistorySize(1)
+	ph.SetCaptureGoroutineStates(true)
+
+	block := make(chan struct{})
+	defer close(block)
+	for i := 0; i < 3; i++ {
+		go func() {
+			<-block
+		}()
+		go func() {
+			select {
+			case <-block:
+			case <-time.After(time.Hour):
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond) // Let them block
+
+	ph.ForwardValueSync("Oh no!")
+
+	states := ph.RecentPanics()[0].GoroutineStates()
+	if states["chan receive"] < 3 || states["select"] < 3 || states["running"] < 1 {
+		t.Errorf("Implausible goroutine states %v", states)
+	}
+}
+
+func TestGoroutineStatesDisabled(t *testing.T) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+	ph.SetHistorySize(1)
+
+	ph.ForwardValueSync("Oh no!")
+	if states := ph.RecentPanics()[0].GoroutineStates(); states != nil {
+		t.Errorf("Goroutine states were captured without being enabled: %v", states)
+	}
+}
+
+func TestCaptureBaseline(t *testing.T) {
+	defer sanepanic.SaveGlobalState()()
+
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	sanepanic.CaptureBaseline()
+
+	block := make(chan struct{})
+	defer close(block)
+	for i := 0; i < 5; i++ {
+		go func() {
+			<-block
+		}()
+	}
+
+	ph.ForwardValue("Oh no!")
+	if n := (<-handled).NewGoroutines; n < 5 {
+		t.Errorf("Expected at least the 5 new goroutines to be counted, got %d", n)
+	}
+}
+
+func TestNoBaseline(t *testing.T) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+	ph.SetHistorySize(1)
+
+	ph.ForwardValueSync("Oh no!")
+	if n := ph.RecentPanics()[0].NewGoroutines; n != 0 {
+		t.Errorf("Counted %d new goroutines without a baseline", n)
+	}
+}
@@ -0,0 +1,76 @@
+package sanepanic
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// A PanicError is an error wrapping a recovered panic, as returned by Group.Wait.
+type PanicError struct {
+	Info Info
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Info.Info)
+}
+
+// Returns the panic value if it was an error, so errors.Is and errors.As see through the panic.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Info.ErrorValue()
+	return err
+}
+
+// A Group runs a set of goroutines working on parts of the same task, in the style of golang.org/x/sync/errgroup.
+// The difference is that a panic in any of them is recovered and reported from Wait as a *PanicError, rather than
+// crashing the process.
+//
+// A zero Group is valid, but doesn't cancel anything on failure.
+type Group struct {
+	wg     sync.WaitGroup
+	cancel context.CancelFunc
+	once   sync.Once
+	err    error
+}
+
+// Returns a new Group along with a context derived from ctx, which is cancelled as soon as a goroutine in the group
+// panics or returns an error, or once Wait returns.
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group{cancel: cancel}, ctx
+}
+
+// Runs f in a new goroutine. The first panic or error from the group's goroutines cancels its context and is returned by Wait.
+func (g *Group) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer func() {
+			if err := recover(); err != nil {
+				g.fail(&PanicError{Info: captureInfo(err, captureOptions{})})
+			}
+		}()
+
+		if err := f(); err != nil {
+			g.fail(err)
+		}
+	}()
+}
+
+func (g *Group) fail(err error) {
+	g.once.Do(func() {
+		g.err = err
+		if g.cancel != nil {
+			g.cancel()
+		}
+	})
+}
+
+// Waits for every goroutine in the group to return, then returns the first panic (as a *PanicError) or error, if any.
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel()
+	}
+	return g.err
+}
@@ -0,0 +1,71 @@
+package sanepanic_test
+
+import (
+	"context"
+	"errors"
+	"github.com/Jragonmiris/sanepanic"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGroupPanic(t *testing.T) {
+	group, ctx := sanepanic.NewGroup(context.Background())
+
+	for i := 0; i < 3; i++ {
+		group.Go(func() error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(5 * time.Second):
+				return errors.New("Wasn't cancelled")
+			}
+		})
+	}
+	group.Go(func() error {
+		panicInNamedFunction()
+		return nil
+	})
+
+	err := group.Wait()
+	var panicErr *sanepanic.PanicError
+	if !errors.As(err, &panicErr) {
+		t.Fatalf("Expected a *PanicError, got %v", err)
+	}
+	if panicErr.Info.Info != "Oh no!" {
+		t.Errorf("Expected the panic value \"Oh no!\", got %v", panicErr.Info.Info)
+	}
+	if !strings.HasSuffix(panicErr.Info.Function, ".panicInNamedFunction") {
+		t.Errorf("Expected the panic to originate in panicInNamedFunction, got %q", panicErr.Info.Function)
+	}
+	if ctx.Err() == nil {
+		t.Errorf("Group's context wasn't cancelled")
+	}
+}
+
+func TestGroupError(t *testing.T) {
+	expected := errors.New("Oh no!")
+	group := &sanepanic.Group{}
+	group.Go(func() error {
+		return expected
+	})
+	group.Go(func() error {
+		return nil
+	})
+
+	if err := group.Wait(); err != expected {
+		t.Errorf("Expected %v, got %v", expected, err)
+	}
+}
+
+func TestPanicErrorUnwrap(t *testing.T) {
+	cause := errors.New("Oh no!")
+	group := &sanepanic.Group{}
+	group.Go(func() error {
+		panic(cause)
+	})
+
+	if err := group.Wait(); !errors.Is(err, cause) {
+		t.Errorf("Expected the error to wrap %v, got %v", cause, err)
+	}
+}
@@ -11,20 +11,51 @@
 package sanepanic
 
 import (
-	"fmt"
-	"os"
-	"runtime"
+	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // The PanicInfo struct roughly contains the data normally printed to terminal
 // on a panic. Info is the exact data returned by recover (which in turn is the data passed into panic(data)).
 //
 // StackTrace is the information returned by runtime.Stack at the time Handler is called. Due to the way panic and defer
-// work in Go, this stack trace will print the line your code panicked on.
+// work in Go, this stack trace will print the line your code panicked on. Frames belonging to sanepanic and the runtime's
+// panic machinery are trimmed from the top of the panicking goroutine's trace, so it starts at the function that panicked.
+//
+// Time is when the panic was forwarded, and GoroutineID is the id the runtime reports for the panicking goroutine
+// (0 if it couldn't be determined). GoroutineName is the name given to it if it was started with GoNamed.
+//
+// Function is the fully qualified name of the function that panicked (or that called ForwardValue), which makes
+// a more stable key for grouping crashes than a file and line number.
+//
+// IsRuntimeError is true if the panic came from the runtime itself (a nil dereference, an index out of range and so on)
+// rather than a call to panic. These almost always indicate a genuine bug.
+//
+// NewGoroutines is how many goroutines were running when the panic was captured that weren't when CaptureBaseline
+// was called, or zero if it never was. A high count suggests a goroutine leak that may be related to the panic.
+//
+// Runtime describes the runtime's parallelism when the panic was captured, see RuntimeInfo.
+//
+// Context is the context passed to ForwardWithContext, if that's how the panic was forwarded. Likewise Deadline is the
+// deadline passed to ForwardWithDeadline, so a handler can tell whether a request panicked before or after it ran out
+// of time, and is zero otherwise.
 type Info struct {
-	Info       interface{}
-	StackTrace string
+	Info           interface{}
+	StackTrace     string
+	Time           time.Time
+	GoroutineID    uint64
+	GoroutineName  string
+	Function       string
+	IsRuntimeError bool
+	NewGoroutines  int
+	Runtime        RuntimeInfo
+	Context        context.Context
+	Deadline       time.Time
+
+	logs   *[]string       // A pointer so Info stays comparable, see RecentLogs
+	states *map[string]int // Likewise, see GoroutineStates
 }
 
 // A HandlerFunc handles a panic and returns true if the panic
@@ -33,22 +64,28 @@ type HandlerFunc func(Info) (keepHandling bool)
 
 var (
 	internalPanicHandler *Handler
-	mu                   *sync.Mutex
+	mu                   sync.Mutex
 )
 
 // Automatically called when the package is imported (but only called once per program execution)
 func init() {
 	internalPanicHandler = NewHandler(DefaultHandlerFunc)
-	mu = &sync.Mutex{}
 }
 
 // Restart should be called if the handler is inadvertantly cancelled.
-// It automatically registers the same HandlerFunc the previous Handler was using
+// It automatically registers the same HandlerFunc the previous Handler was using, along with the rest of its configuration
 func Restart() {
 	mu.Lock()
 	defer mu.Unlock()
-	internalPanicHandler.Done()
-	internalPanicHandler = NewHandler(internalPanicHandler.handle)
+	restart()
+	watchForStop()
+}
+
+// Replaces the package's handler with a fresh one. Expects mu to be held.
+func restart() {
+	old := internalPanicHandler
+	old.Done()
+	internalPanicHandler = old.cloneWith(old.config()) // Unlike Clone, keeps track of whether the first panic hook has run
 }
 
 // Allows you to tailor your recovery function to the PanicInfo forwarded to the listener
@@ -59,26 +96,114 @@ func SetHandlerFunc(newHandler HandlerFunc) {
 	internalPanicHandler.SetHandlerFunc(newHandler)
 }
 
+// Returns the HandlerFunc the package's listener is currently using, so it can be wrapped and reinstalled
+// with SetHandlerFunc.
+func CurrentHandlerFunc() HandlerFunc {
+	mu.Lock()
+	defer mu.Unlock()
+	return internalPanicHandler.HandlerFunc()
+}
+
+// Installs newHandler on the package's listener and returns the HandlerFunc it replaced. See Handler.SwapHandlerFunc.
+func SwapHandlerFunc(newHandler HandlerFunc) (old HandlerFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	return internalPanicHandler.SwapHandlerFunc(newHandler)
+}
+
+// Sets a function that every Info forwarded to the package's listener passes through before it's handled.
+// See Handler.SetRedactor.
+func SetRedactor(redactor func(Info) Info) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.SetRedactor(redactor)
+}
+
+// Installs a HandlerFunc that takes over handling panics once the current one returns false.
+// See Handler.SetFallbackHandlerFunc for details.
+func SetFallbackHandlerFunc(fallback HandlerFunc) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.SetFallbackHandlerFunc(fallback)
+}
+
 // Exits the listener if no panics have been received, or waits until panic handling has been done
 // otherwise
 func Done() {
 	mu.Lock()
 	defer mu.Unlock()
+	stopWatchingForStop()
 	internalPanicHandler.Done()
 }
 
 // At the beginning of any Goroutine, call "defer sanepanic.Handler()"
 // to forward the panic to the package's listener and call your cleanup handling function
 func Forward() {
+	err := recover() // Have to do recover directly in deferred function
+	packageHandler().forward(err)
+}
+
+// Call "defer sanepanic.ForwardAndDone(wg)" in place of separate "defer wg.Done()" and "defer sanepanic.Forward()"
+// calls. The panic (if any) is always forwarded before wg.Done is called, so once wg.Wait returns every panic
+// from the group has been handed to the listener. Note that the HandlerFunc may still be processing it.
+func ForwardAndDone(wg *sync.WaitGroup) {
+	defer wg.Done()
+	err := recover()
+	packageHandler().forward(err)
+}
+
+// Forwards v to the package's listener as if it had been recovered from a panic. This is useful if you've already
+// recovered the value yourself and want it to go through the central handler. Nil values are ignored.
+func ForwardValue(v interface{}) {
+	packageHandler().forward(v)
+}
+
+// The same as ForwardValue, except it doesn't return until the HandlerFunc has finished processing v (or the listener
+// has stopped without receiving it). This is useful if, say, you need a crash log to be written before shutting down.
+func ForwardValueSync(v interface{}) {
+	packageHandler().ForwardValueSync(v)
+}
+
+// Forwards v to the package's listener with a stack trace captured elsewhere, e.g. by debug.Stack in another
+// recovery point. See Handler.ForwardValueWithStack.
+func ForwardValueWithStack(v interface{}, stack string) {
+	packageHandler().ForwardValueWithStack(v, stack)
+}
+
+// Call "defer sanepanic.ForwardTo(handlers...)" to forward a panic to several handlers at once, for panics that are
+// relevant to more than one part of a process. The panic is captured once, using the first handler's stack settings,
+// and the same Info is sent to each handler to deal with according to its own configuration. The package's listener
+// isn't included unless you're using it as one of the handlers.
+func ForwardTo(handlers ...*Handler) {
+	err := recover() // Have to do recover directly in deferred function
+	if err == nil || len(handlers) == 0 {
+		return
+	}
+	info := handlers[0].newInfo(err, 0)
+	for _, ph := range handlers {
+		if !ph.nopMode() {
+			ph.send(forwardedPanic{info: info})
+		}
+	}
+}
+
+// Returns the package's handler. Anything that forwards or waits has to go through this rather than holding mu, since
+// the send can block on a busy listener, and a HandlerFunc that forwards in turn would then wait on mu forever.
+func packageHandler() *Handler {
 	mu.Lock()
 	defer mu.Unlock()
-	err := recover() // Have to do recover directly in deferred function
-	internalPanicHandler.forward(err)
+	return internalPanicHandler
 }
 
 // Prints a panic almost exactly like the runtime does, except the program doesn't exit.
+// Output goes to stderr unless changed with SetOutput.
 func DefaultHandlerFunc(info Info) bool {
-	fmt.Fprintf(os.Stderr, "Panic: %v\n%s", info.Info, info.StackTrace)
+	writeInfo("", info)
+	return true
+}
+
+// Silently swallows panics, the opposite of DefaultHandlerFunc. Handy in tests and benchmarks.
+func NopHandlerFunc(Info) bool {
 	return true
 }
 
@@ -87,50 +212,163 @@ func DefaultHandlerFunc(info Info) bool {
 // A central processor for panicking. This more or less duplicates the functionality of the package.
 // The only missing function is Restart() which can be emulated by calling YourPanicHandler.Done() followed by creating
 // a new one.
+//
+// The zero Handler is ready to use: it starts listening the first time a panic is forwarded to it (or it's stopped),
+// using DefaultHandlerFunc unless another HandlerFunc has been set by then.
 type Handler struct {
-	panicChan chan Info
-	quit      chan struct{}
-	handle    HandlerFunc
-	mu        *sync.Mutex
+	pending    int64  // Panics sent but not yet handled, accessed atomically so it's kept first for alignment
+	listener   uint64 // Id of the listener's goroutine, accessed atomically
+	dropped    uint64 // Panics a notify handler couldn't deliver, accessed atomically
+	timeouts   uint64 // Drains that have timed out, accessed atomically
+	nop        int32  // Non-zero in nop mode, accessed atomically
+	panicChan  chan forwardedPanic
+	quit       chan struct{}  // Closed once the listener has stopped
+	stop       chan struct{}  // Closed to tell the listener to stop
+	resumed    chan struct{}  // Signalled when the listener should pick up held panics
+	lastDrop   atomic.Value   // The dropper of the last drain to time out, for batches collected before it did
+	listenWith func(*Handler) // Replaces listen for handlers that work differently, e.g. batch handlers
+	startOnce  sync.Once
+	stopOnce   sync.Once
+	handle     HandlerFunc
+	batch      func([]Info) bool // The batch function of a batch handler, until a HandlerFunc replaces it
+	fallback   HandlerFunc
+	metrics    MetricsSink
+	chain      []chainedHandlerFunc
+	flushers   []Flusher
+	redact     func(Info) Info
+	firstPanic *sync.Once // Shared with clones, so restarting doesn't make the next panic the first again
+	onFirst    func(Info)
+	breaker    circuitBreaker
+	waiters    []chan Info
+	waitMu     sync.Mutex // Guards waiters separately so waiting doesn't contend with handling
+	history    history
+	capture    captureOptions
+	captureMu  sync.Mutex    // Guards capture, which is read when forwarding rather than when handling
+	dropper    atomic.Value  // The func(Info) panics are dropped to while a timed out drain clears the backlog
+	idle       chan struct{} // Closed once nothing is pending, for Drain to wait on
+	idleMu     sync.Mutex
+	paused     bool
+	held       []forwardedPanic // Panics received while paused
+	pauseMu    sync.Mutex
+	mu         sync.Mutex
 }
 
 // Creates a new panic handler AND makes it start listening for panics.
 func NewHandler(handler HandlerFunc) *Handler {
-	ph := &Handler{panicChan: make(chan Info), handle: handler, mu: &sync.Mutex{}, quit: make(chan struct{})}
-	go ph.listen()
+	ph := &Handler{handle: handler}
+	ph.start()
 	return ph
 }
 
+// Sets up the channels and starts the listener, unless that's already been done.
+func (ph *Handler) start() {
+	ph.startOnce.Do(func() {
+		ph.panicChan = make(chan forwardedPanic)
+		ph.quit = make(chan struct{})
+		ph.stop = make(chan struct{})
+		ph.resumed = make(chan struct{}, 1)
+
+		ph.mu.Lock()
+		if ph.handle == nil {
+			ph.handle = DefaultHandlerFunc
+		}
+		ph.mu.Unlock()
+
+		if ph.listenWith != nil {
+			go ph.listenWith(ph)
+		} else {
+			go ph.listen()
+		}
+	})
+}
+
 // Handles panics
 func (ph *Handler) listen() {
-	for info := range ph.panicChan {
-		if !ph.handleForwardedPanic(info) {
-			close(ph.quit)
-			break
+	defer close(ph.quit)
+	atomic.StoreUint64(&ph.listener, currentGoroutineID())
+	for {
+		select {
+		case fp := <-ph.panicChan:
+			if ph.dropIfDraining(fp) || ph.answerProbe(fp) || ph.hold(fp) {
+				continue
+			}
+			if !ph.handleAll(append(ph.release(), fp)) {
+				return
+			}
+		case <-ph.resumed:
+			if !ph.handleAll(ph.release()) {
+				return
+			}
+		case <-ph.stop:
+			ph.handleAll(ph.releaseAll()) // Even if we're paused
+			return
 		}
 	}
 }
 
-func (ph *Handler) handleForwardedPanic(info Info) bool {
+// Handles each panic in turn, and returns false once the listener should stop. Any panics left over at that point
+// pass silently.
+func (ph *Handler) handleAll(fps []forwardedPanic) bool {
+	for i, fp := range fps {
+		if !ph.handleForwardedPanic(fp) && !ph.fallBack() {
+			for _, dropped := range fps[i+1:] {
+				ph.settle(dropped)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+func (ph *Handler) handleForwardedPanic(fp forwardedPanic) bool {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	defer ph.settle(fp)
+	ph.breaker.record(time.Now())
+	info := ph.observe(fp.info)
+	ph.flush()
+	keepHandling := ph.runHandlerFuncs(info)
+	ph.history.add(info)
+	ph.notifyWaiters(info)
+	return keepHandling
+}
+
+// Applies the redactor, reports the panic to the metrics sink and runs the first panic hook. Expects ph.mu to be held.
+func (ph *Handler) observe(info Info) Info {
+	if ph.redact != nil {
+		info = ph.redact(info)
+	}
+	if ph.metrics != nil {
+		ph.metrics.PanicObserved(info)
+	}
+	ph.firstPanicOnce().Do(func() {
+		if ph.onFirst != nil {
+			ph.onFirst(info)
+		}
+	})
+	return info
+}
+
+// Switches over to the fallback HandlerFunc if there is one, returns false if the listener should stop.
+func (ph *Handler) fallBack() bool {
 	ph.mu.Lock()
 	defer ph.mu.Unlock()
-	return ph.handle(info)
+	if ph.fallback == nil {
+		return false
+	}
+	ph.handle, ph.fallback, ph.batch = ph.fallback, nil, nil
+	return true
 }
 
 // Stops the listener (if it has not already been used). If a panic has been detected, waits for the processing to be done
 // before proceeding
 func (ph *Handler) Done() {
-	select {
-	case info, ok := <-ph.panicChan: // Handles the case where we somehow do this exactly when a panic is sent
-		if ok {
-			close(ph.quit)
-			close(ph.panicChan)
-			ph.mu.Lock()
-			defer ph.mu.Unlock()
-			ph.handleForwardedPanic(info)
-		}
-	default: // Only executes if no panics were sent AND panicChan has yet to be closed
-		close(ph.panicChan)
+	ph.start()
+	ph.stopOnce.Do(func() {
+		close(ph.stop)
+	})
+	if !ph.onListener() { // Called by a HandlerFunc, the listener stops once it returns
+		<-ph.quit
 	}
 }
 
@@ -138,7 +376,42 @@ func (ph *Handler) Done() {
 func (ph *Handler) SetHandlerFunc(newHandler HandlerFunc) {
 	ph.mu.Lock()
 	defer ph.mu.Unlock()
-	ph.handle = newHandler
+	ph.handle, ph.batch = newHandler, nil
+}
+
+// Installs newHandler and returns the HandlerFunc it replaced, in one step so nothing can be installed in between.
+// A panic the listener is already handling is finished by the old HandlerFunc before the swap happens, and every
+// panic after it goes to newHandler, so during live reconfiguration each panic is handled by exactly one of them.
+func (ph *Handler) SwapHandlerFunc(newHandler HandlerFunc) (old HandlerFunc) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	old, ph.handle, ph.batch = ph.handle, newHandler, nil
+	return old
+}
+
+// Returns the HandlerFunc currently installed on this handler, so it can be wrapped and reinstalled
+// with SetHandlerFunc.
+func (ph *Handler) HandlerFunc() HandlerFunc {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	return ph.handle
+}
+
+// Sets a function that every Info passes through before anything else sees it, allowing you to scrub secrets
+// from the recovered value or trim the stack trace. Nil disables it.
+func (ph *Handler) SetRedactor(redactor func(Info) Info) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.redact = redactor
+}
+
+// Installs a HandlerFunc that takes over once the current one returns false, rather than letting any later panics
+// pass silently. The fallback is only used once; if it returns false as well the listener stops for good.
+// It has no effect once the listener has already stopped, or if it's stopped with Done.
+func (ph *Handler) SetFallbackHandlerFunc(fallback HandlerFunc) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.fallback = fallback
 }
 
 // As with the package level function, calling defer YourPanicHandler.Forward()
@@ -148,14 +421,95 @@ func (ph *Handler) Forward() {
 	ph.forward(err)
 }
 
-func (ph *Handler) forward(err interface{}) {
-	if err != nil {
-		buf := make([]byte, 10000)
-		traceSize := runtime.Stack(buf, true)
-		buf = buf[:traceSize]
-		select {
-		case ph.panicChan <- Info{Info: err, StackTrace: string(buf)}:
-		case <-ph.quit:
+// As with the package level function, "defer YourPanicHandler.ForwardAndDone(wg)" forwards the panic
+// to this handler before calling wg.Done.
+func (ph *Handler) ForwardAndDone(wg *sync.WaitGroup) {
+	defer wg.Done()
+	err := recover()
+	ph.forward(err)
+}
+
+// Forwards v to this handler as if it had been recovered from a panic.
+func (ph *Handler) ForwardValue(v interface{}) {
+	ph.forward(v)
+}
+
+// Forwards v to this handler and blocks until the HandlerFunc has returned for it. If the listener stops before
+// receiving v this returns immediately.
+func (ph *Handler) ForwardValueSync(v interface{}) {
+	if v != nil && !ph.nopMode() {
+		done := make(chan struct{})
+		if ph.send(forwardedPanic{info: ph.newInfo(v, 0), done: done}) {
+			<-done
 		}
 	}
 }
+
+// Forwards v to this handler using stack, verbatim, as its stack trace instead of capturing one. This is for bridging
+// from code that has already recovered a panic and captured its stack, where capturing again would only show the
+// bridge. If stack is empty one is captured as with ForwardValue.
+func (ph *Handler) ForwardValueWithStack(v interface{}, stack string) {
+	if v == nil || ph.nopMode() {
+		return
+	}
+	if stack == "" {
+		ph.forward(v)
+		return
+	}
+	ph.send(forwardedPanic{info: captureInfoWithStack(v, stack, ph.captureOptions())})
+}
+
+func (ph *Handler) forward(err interface{}) {
+	ph.forwardSkip(err, 0)
+}
+
+func (ph *Handler) forwardSkip(err interface{}, skip int) {
+	if err != nil && !ph.nopMode() {
+		ph.send(forwardedPanic{info: ph.newInfo(err, skip)})
+	}
+}
+
+// Hands a panic to the listener, returns false if the listener quit before taking it.
+func (ph *Handler) send(fp forwardedPanic) bool {
+	ph.start()
+	if ph.onListener() {
+		// A HandlerFunc panicked (or forwarded something) itself. The listener is busy with it, so waiting on
+		// the listener would deadlock; report it directly instead.
+		logf("panic while handling a panic\n%s", FormatInfo(fp.info))
+		fp.finish()
+		return false
+	}
+	atomic.AddInt64(&ph.pending, 1)
+	select {
+	case ph.panicChan <- fp:
+		return true
+	case <-ph.quit:
+		ph.unpend()
+		return false
+	}
+}
+
+// Whether we're running on the listener's goroutine, i.e. inside a HandlerFunc.
+func (ph *Handler) onListener() bool {
+	return atomic.LoadUint64(&ph.listener) == currentGoroutineID()
+}
+
+// Marks a panic taken off the channel as dealt with.
+func (ph *Handler) settle(fp forwardedPanic) {
+	fp.finish()
+	ph.unpend()
+}
+
+// An Info on its way to the listener. Done is closed once the HandlerFunc returns, it's nil unless somebody
+// is waiting on it.
+type forwardedPanic struct {
+	info  Info
+	done  chan struct{}
+	probe bool // Sent by SelfTest, answered by the listener without being handled
+}
+
+func (fp forwardedPanic) finish() {
+	if fp.done != nil {
+		close(fp.done)
+	}
+}
@@ -0,0 +1,129 @@
+package sanepanic
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SampleHandlerFunc wraps inner so that each panic is only passed along with probability rate (1.0 means always,
+// 0 means never). Panics that aren't sampled are dropped and the handler keeps running. This is useful if panics are
+// so frequent that handling every single one isn't worth it.
+func SampleHandlerFunc(inner HandlerFunc, rate float64) HandlerFunc {
+	return SampleHandlerFuncRand(inner, rate, rand.New(rand.NewSource(time.Now().UnixNano())))
+}
+
+// The same as SampleHandlerFunc, except the sampling decisions are drawn from r. Give it a fixed seed
+// if you need the sampling to be deterministic (e.g. in tests).
+func SampleHandlerFuncRand(inner HandlerFunc, rate float64, r *rand.Rand) HandlerFunc {
+	mu := &sync.Mutex{} // rand.Rand isn't safe for concurrent use, and the HandlerFunc may be shared between Handlers
+	return func(info Info) bool {
+		mu.Lock()
+		sampled := r.Float64() < rate
+		mu.Unlock()
+
+		if !sampled {
+			return true
+		}
+		return inner(info)
+	}
+}
+
+// FileDumpHandlerFunc returns a HandlerFunc that writes each panic, as formatted by FormatInfo, to a new file in dir
+// named after the time of the panic (e.g. panic-20060102-150405.log). The directory is created if it doesn't exist.
+// Failing to write the file is logged to the output set with SetOutput rather than panicking,
+// and the handler always keeps running.
+func FileDumpHandlerFunc(dir string) HandlerFunc {
+	return func(info Info) bool {
+		if err := dumpToFile(dir, info); err != nil {
+			logf("could not write crash dump: %v\n%s", err, FormatInfo(info))
+		}
+		return true
+	}
+}
+
+func dumpToFile(dir string, info Info) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	when := info.Time
+	if when.IsZero() {
+		when = time.Now()
+	}
+	name := "panic-" + when.Format("20060102-150405")
+
+	// Several panics in the same second each get their own file
+	path := filepath.Join(dir, name+".log")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	for i := 1; os.IsExist(err); i++ {
+		path = filepath.Join(dir, fmt.Sprintf("%s-%d.log", name, i))
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.WriteString(FormatInfo(info)); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// TeeHandlerFunc returns a HandlerFunc that passes each panic to every one of handlers. Unlike registered HandlerFuncs,
+// every handler always runs: one returning false doesn't stop the rest, and one that panics is recovered (and logged)
+// so it can't break the others. The handler keeps running only if all of them return true; one that panicked
+// counts as true. The handlers run in the order given, one after the other, so each sees anything the earlier ones wrote.
+func TeeHandlerFunc(handlers ...HandlerFunc) HandlerFunc {
+	return func(info Info) bool {
+		keepHandling := true
+		for _, handler := range handlers {
+			if !runIsolated(handler, info) {
+				keepHandling = false
+			}
+		}
+		return keepHandling
+	}
+}
+
+func runIsolated(handler HandlerFunc, info Info) (keepHandling bool) {
+	defer func() {
+		if err := recover(); err != nil {
+			logf("HandlerFunc panicked: %v", err)
+			keepHandling = true
+		}
+	}()
+	return handler(info)
+}
+
+// The number of frames CompactHandlerFunc lists.
+const compactFrames = 5
+
+// CompactHandlerFunc returns a HandlerFunc that writes each panic to w as a single line, for log collectors that treat
+// every line as a separate event. The line holds the time, goroutine id, panicking function, panic value and the top
+// few stack frames, followed by the full stack trace escaped as a quoted string:
+//
+//	time=2006-01-02T15:04:05Z goroutine=7 function=main.main panic="Oh no!" frames="main.main /tmp/main.go:5" trace="..."
+func CompactHandlerFunc(w io.Writer) HandlerFunc {
+	mu := &sync.Mutex{} // The HandlerFunc may be shared between Handlers
+	return func(info Info) bool {
+		line := fmt.Sprintf("time=%s goroutine=%d function=%s panic=%q frames=%q trace=%q\n",
+			info.Time.Format(time.RFC3339Nano),
+			info.GoroutineID,
+			info.Function,
+			fmt.Sprint(info.Info),
+			strings.Join(stackFrames(info.StackTrace, compactFrames), " | "),
+			info.StackTrace)
+
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, line)
+		return true
+	}
+}
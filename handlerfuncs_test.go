@@ -0,0 +1,162 @@
+package sanepanic_test
+
+import (
+	"bytes"
+	"github.com/Jragonmiris/sanepanic"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSampleHandlerFunc(t *testing.T) {
+	const total = 10000
+	const rate = 0.25
+
+	reached := 0
+	inner := func(sanepanic.Info) bool {
+		reached++
+		return true
+	}
+
+	sample := sanepanic.SampleHandlerFuncRand(inner, rate, rand.New(rand.NewSource(42)))
+	for i := 0; i < total; i++ {
+		if !sample(sanepanic.Info{Info: i}) {
+			t.Fatalf("Sampled handler asked to stop handling")
+		}
+	}
+
+	if fraction := float64(reached) / total; fraction < rate-0.02 || fraction > rate+0.02 {
+		t.Errorf("Expected roughly %v of panics to be handled, got %v", rate, fraction)
+	}
+}
+
+func TestSampleHandlerFuncAlways(t *testing.T) {
+	reached := 0
+	sample := sanepanic.SampleHandlerFunc(func(sanepanic.Info) bool {
+		reached++
+		return true
+	}, 1.0)
+
+	for i := 0; i < 100; i++ {
+		sample(sanepanic.Info{Info: i})
+	}
+
+	if reached != 100 {
+		t.Errorf("Expected every panic to be handled with a rate of 1, got %d", reached)
+	}
+}
+
+func TestFileDumpHandlerFunc(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "crashes")
+	dump := sanepanic.FileDumpHandlerFunc(dir)
+
+	when := time.Date(2014, time.March, 1, 12, 30, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if !dump(sanepanic.Info{Info: "Oh no!", StackTrace: "goroutine 7 [running]:", Time: when}) {
+			t.Fatalf("File dump handler asked to stop handling")
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "panic-20140301-123000*.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 crash dumps, found %v", files)
+	}
+
+	for _, file := range files {
+		contents, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(contents), "panic: Oh no!") || !strings.Contains(string(contents), "goroutine 7 [running]:") {
+			t.Errorf("Crash dump %s is missing the panic details:\n%s", file, contents)
+		}
+	}
+}
+
+func TestFileDumpHandlerFuncBadDir(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The directory can't be created since a file is in the way
+	if !sanepanic.FileDumpHandlerFunc(filepath.Join(file, "crashes"))(sanepanic.Info{Info: "Oh no!"}) {
+		t.Errorf("File dump handler asked to stop handling after failing to write")
+	}
+}
+
+func TestTeeHandlerFunc(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	defer sanepanic.SetOutput(os.Stderr)
+
+	observed := []interface{}{}
+	record := func(info sanepanic.Info) bool {
+		observed = append(observed, info.Info)
+		return true
+	}
+	tee := sanepanic.TeeHandlerFunc(record, func(sanepanic.Info) bool {
+		panic("Broken handler")
+	}, record)
+
+	if !tee(sanepanic.Info{Info: "Oh no!"}) {
+		t.Errorf("Tee asked to stop handling when no handler returned false")
+	}
+	if len(observed) != 2 || observed[0] != "Oh no!" || observed[1] != "Oh no!" {
+		t.Errorf("Expected both working handlers to observe the panic, got %v", observed)
+	}
+	if !strings.Contains(buf.String(), "Broken handler") {
+		t.Errorf("Panicking handler wasn't logged, output was:\n%s", buf)
+	}
+}
+
+func TestTeeHandlerFuncStops(t *testing.T) {
+	ran := 0
+	tee := sanepanic.TeeHandlerFunc(func(sanepanic.Info) bool {
+		ran++
+		return false
+	}, func(sanepanic.Info) bool {
+		ran++
+		return true
+	})
+
+	if tee(sanepanic.Info{Info: "Oh no!"}) {
+		t.Errorf("Tee kept handling when a handler returned false")
+	}
+	if ran != 2 {
+		t.Errorf("Expected both handlers to run, %d did", ran)
+	}
+}
+
+func TestCompactHandlerFunc(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ph := sanepanic.NewHandler(sanepanic.CompactHandlerFunc(buf))
+	defer ph.Done()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer ph.ForwardAndDone(wg)
+		panic("Oh no!\nTwo lines!")
+	}()
+	wg.Wait()
+	ph.Drain(5 * time.Second)
+
+	line := buf.String()
+	if strings.Count(line, "\n") != 1 || !strings.HasSuffix(line, "\n") {
+		t.Fatalf("Expected exactly one line, got:\n%s", line)
+	}
+	if !strings.Contains(line, `panic="Oh no!\nTwo lines!"`) {
+		t.Errorf("Line doesn't contain the escaped panic value:\n%s", line)
+	}
+	if !strings.Contains(line, "frames=\"github.com/Jragonmiris/sanepanic_test.TestCompactHandlerFunc.func1 ") {
+		t.Errorf("Line doesn't start the frames at the panicking function:\n%s", line)
+	}
+}
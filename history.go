@@ -0,0 +1,85 @@
+package sanepanic
+
+import (
+	"sync"
+)
+
+// A fixed size ring buffer of the most recently handled panics. The zero history retains nothing.
+type history struct {
+	mu    sync.Mutex
+	infos []Info
+	start int // Index of the oldest Info
+	count int
+}
+
+func (h *history) setSize(n int) {
+	if n < 0 {
+		n = 0
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	recent := h.recent()
+	if len(recent) > n {
+		recent = recent[len(recent)-n:]
+	}
+	h.infos = make([]Info, n)
+	h.start = 0
+	h.count = copy(h.infos, recent)
+}
+
+func (h *history) size() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.infos)
+}
+
+func (h *history) add(info Info) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.infos) == 0 {
+		return
+	}
+	if h.count < len(h.infos) {
+		h.infos[(h.start+h.count)%len(h.infos)] = info
+		h.count++
+	} else {
+		h.infos[h.start] = info
+		h.start = (h.start + 1) % len(h.infos)
+	}
+}
+
+// Returns a copy of the retained panics, oldest first. Expects h.mu to be held.
+func (h *history) recent() []Info {
+	recent := make([]Info, h.count)
+	for i := range recent {
+		recent[i] = h.infos[(h.start+i)%len(h.infos)]
+	}
+	return recent
+}
+
+// Sets how many of the most recently handled panics the package's listener remembers. See Handler.SetHistorySize.
+func SetHistorySize(n int) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.SetHistorySize(n)
+}
+
+// Returns the most recent panics handled by the package's listener. See Handler.RecentPanics.
+func RecentPanics() []Info {
+	mu.Lock()
+	defer mu.Unlock()
+	return internalPanicHandler.RecentPanics()
+}
+
+// Sets how many of the most recently handled panics this handler remembers for RecentPanics, e.g. for a
+// "recent crashes" view. Shrinking the history keeps the newest panics, and 0 (the default) or less turns it off.
+func (ph *Handler) SetHistorySize(n int) {
+	ph.history.setSize(n)
+}
+
+// Returns copies of the most recent panics this handler has handled, oldest first.
+func (ph *Handler) RecentPanics() []Info {
+	ph.history.mu.Lock()
+	defer ph.history.mu.Unlock()
+	return ph.history.recent()
+}
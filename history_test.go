@@ -0,0 +1,55 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"testing"
+)
+
+func TestRecentPanics(t *testing.T) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+	ph.SetHistorySize(3)
+
+	for i := 0; i < 5; i++ {
+		ph.ForwardValueSync(i)
+	}
+
+	recent := ph.RecentPanics()
+	if len(recent) != 3 {
+		t.Fatalf("Expected 3 retained panics, got %d", len(recent))
+	}
+	for i, info := range recent {
+		if info.Info != i+2 {
+			t.Errorf("Expected retained panic %d to be %d, got %v", i, i+2, info.Info)
+		}
+	}
+
+	ph.SetHistorySize(2)
+	if recent := ph.RecentPanics(); len(recent) != 2 || recent[0].Info != 3 || recent[1].Info != 4 {
+		t.Errorf("Shrinking the history didn't keep the newest panics, got %v", recent)
+	}
+}
+
+func TestRecentPanicsDisabled(t *testing.T) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+
+	ph.ForwardValueSync("Oh no!")
+
+	if recent := ph.RecentPanics(); len(recent) != 0 {
+		t.Errorf("Panics were retained with no history size set: %v", recent)
+	}
+}
+
+func TestNegativeHistorySize(t *testing.T) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+	ph.SetHistorySize(3)
+	ph.ForwardValueSync("Oh no!")
+
+	ph.SetHistorySize(-1)
+	ph.ForwardValueSync("Oh no!")
+	if recent := ph.RecentPanics(); len(recent) != 0 {
+		t.Errorf("Expected a negative size to turn the history off, got %v", recent)
+	}
+}
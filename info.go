@@ -0,0 +1,86 @@
+package sanepanic
+
+import (
+	"fmt"
+)
+
+// StringValue returns the recovered value if it's a string, as with panic("message").
+func (info Info) StringValue() (string, bool) {
+	return As[string](info)
+}
+
+// ErrorValue returns the recovered value if it's an error. This includes runtime errors
+// such as nil dereferences and out of range indexing.
+func (info Info) ErrorValue() (error, bool) {
+	return As[error](info)
+}
+
+// As type-asserts the recovered value to T, saving you from writing the same type switch in every HandlerFunc.
+func As[T any](info Info) (T, bool) {
+	v, ok := info.Info.(T)
+	return v, ok
+}
+
+// Causes returns the errors that make up the recovered value, so a HandlerFunc can report each one. If the value
+// joins several errors (it has an "Unwrap() []error" method, as errors.Join's result does) they're returned in order,
+// with any nested joins flattened. Any other error is returned on its own, and if the value isn't an error at all
+// Causes returns nil.
+func (info Info) Causes() []error {
+	err, ok := info.ErrorValue()
+	if !ok {
+		return nil
+	}
+	return flattenErrors(err, nil)
+}
+
+func flattenErrors(err error, causes []error) []error {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return append(causes, err)
+	}
+	for _, cause := range joined.Unwrap() {
+		if cause != nil {
+			causes = flattenErrors(cause, causes)
+		}
+	}
+	return causes
+}
+
+// String summarizes the panic on one line, e.g. "panic: Oh no! (in main.doWork)", so an Info printed with %v doesn't
+// dump the whole struct and stack trace into a log. Use FormatInfo for the full report.
+func (info Info) String() string {
+	if info.Function == "" {
+		return fmt.Sprintf("panic: %v", info.Info)
+	}
+	return fmt.Sprintf("panic: %v (in %s)", info.Info, info.Function)
+}
+
+// Repanic re-raises the panic, for layered handling where an inner level decides a panic isn't its concern and leaves
+// it to an outer one. The panic value is a *RepanicError carrying the Info, so when it's recovered and forwarded again
+// the outer handler gets the original value, stack trace and everything else rather than a trace of the inner level.
+func (info Info) Repanic() {
+	panic(&RepanicError{Value: info.Info, info: info})
+}
+
+// A RepanicError is the panic value raised by Info.Repanic. Value is the original panic value; code recovering it
+// without sanepanic's help can get at it there, or with errors.Is and errors.As if it was an error. It's a StackTracer,
+// so the original stack trace isn't lost either.
+type RepanicError struct {
+	Value interface{}
+	info  Info
+}
+
+func (e *RepanicError) Error() string {
+	return fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the original panic value if it's an error, otherwise nil.
+func (e *RepanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// StackTrace returns the stack trace of the original panic.
+func (e *RepanicError) StackTrace() string {
+	return e.info.StackTrace
+}
@@ -0,0 +1,157 @@
+package sanepanic_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/Jragonmiris/sanepanic"
+	"testing"
+)
+
+type customPanic struct {
+	code int
+}
+
+func TestStringValue(t *testing.T) {
+	if s, ok := (sanepanic.Info{Info: "Oh no!"}).StringValue(); !ok || s != "Oh no!" {
+		t.Errorf("Expected string value \"Oh no!\", got %q (ok: %v)", s, ok)
+	}
+
+	if _, ok := (sanepanic.Info{Info: errors.New("Oh no!")}).StringValue(); ok {
+		t.Errorf("An error was reported as a string value")
+	}
+}
+
+func TestErrorValue(t *testing.T) {
+	err := errors.New("Oh no!")
+	if e, ok := (sanepanic.Info{Info: err}).ErrorValue(); !ok || e != err {
+		t.Errorf("Expected error value %v, got %v (ok: %v)", err, e, ok)
+	}
+
+	if _, ok := (sanepanic.Info{Info: "Oh no!"}).ErrorValue(); ok {
+		t.Errorf("A string was reported as an error value")
+	}
+}
+
+func TestAs(t *testing.T) {
+	info := sanepanic.Info{Info: customPanic{code: 3}}
+	if v, ok := sanepanic.As[customPanic](info); !ok || v.code != 3 {
+		t.Errorf("Expected custom panic value with code 3, got %v (ok: %v)", v, ok)
+	}
+
+	if s, ok := sanepanic.As[string](sanepanic.Info{Info: "Oh no!"}); !ok || s != "Oh no!" {
+		t.Errorf("Expected string value \"Oh no!\", got %q (ok: %v)", s, ok)
+	}
+
+	if e, ok := sanepanic.As[error](sanepanic.Info{Info: errors.New("Oh no!")}); !ok || e == nil {
+		t.Errorf("Expected an error value, got %v (ok: %v)", e, ok)
+	}
+
+	if _, ok := sanepanic.As[customPanic](sanepanic.Info{Info: 3}); ok {
+		t.Errorf("An int was reported as a custom panic value")
+	}
+}
+
+func TestCauses(t *testing.T) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+
+	first, second, third := errors.New("first"), errors.New("second"), errors.New("third")
+	var info sanepanic.Info
+	func() {
+		defer func() {
+			info, _ = ph.ForwardReturning(recover())
+		}()
+		panic(errors.Join(first, errors.Join(second, third)))
+	}()
+
+	causes := info.Causes()
+	if len(causes) != 3 || causes[0] != first || causes[1] != second || causes[2] != third {
+		t.Errorf("Expected the three joined errors, got %v", causes)
+	}
+
+	if causes := (sanepanic.Info{Info: first}).Causes(); len(causes) != 1 || causes[0] != first {
+		t.Errorf("Expected a plain error to be its own cause, got %v", causes)
+	}
+	if causes := (sanepanic.Info{Info: "Oh no!"}).Causes(); causes != nil {
+		t.Errorf("Expected no causes for a string panic, got %v", causes)
+	}
+}
+
+func TestInfoString(t *testing.T) {
+	info := sanepanic.Info{
+		Info:       "Oh no!",
+		StackTrace: "goroutine 1 [running]:\nmain.doWork()\n\t/src/main.go:20 +0x25\n",
+		Function:   "main.doWork",
+	}
+	if s := fmt.Sprintf("%v", info); s != "panic: Oh no! (in main.doWork)" {
+		t.Errorf("Unexpected summary %q", s)
+	}
+	if s := fmt.Sprint(sanepanic.Info{Info: 42}); s != "panic: 42" {
+		t.Errorf("Unexpected summary without a function %q", s)
+	}
+
+	same := info
+	if info != same {
+		t.Errorf("Infos stopped being comparable")
+	}
+}
+
+func TestRepanic(t *testing.T) {
+	outerHandled := make(chan sanepanic.Info, 1)
+	outer := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		outerHandled <- info
+		return true
+	})
+	defer outer.Done()
+	inner := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer inner.Done()
+
+	var original sanepanic.Info
+	go func() {
+		defer outer.Forward()
+		func() {
+			defer func() {
+				if info, ok := inner.ForwardReturning(recover()); ok {
+					original = info
+					info.Repanic() // Not the inner level's concern
+				}
+			}()
+			panic("Oh no!")
+		}()
+	}()
+
+	handled := <-outerHandled
+	if handled.Info != "Oh no!" {
+		t.Errorf("Outer handler got %v rather than the original panic value", handled.Info)
+	}
+	if handled.StackTrace != original.StackTrace || handled.Function != original.Function {
+		t.Errorf("Outer handler lost the original stack, got:\n%s", handled.StackTrace)
+	}
+}
+
+func TestRepanicValue(t *testing.T) {
+	cause := errors.New("Oh no!")
+	original := sanepanic.Info{Info: cause, StackTrace: "goroutine 7 [running]:\noriginal.origin()\n"}
+
+	var recovered interface{}
+	func() {
+		defer func() {
+			recovered = recover()
+		}()
+		original.Repanic()
+	}()
+
+	repanicked, ok := recovered.(*sanepanic.RepanicError)
+	if !ok {
+		t.Fatalf("Expected a *RepanicError, recovered %T", recovered)
+	}
+	if repanicked.Value != cause || !errors.Is(repanicked, cause) {
+		t.Errorf("Original value isn't reachable from %v", repanicked)
+	}
+	if repanicked.StackTrace() != original.StackTrace {
+		t.Errorf("Original stack trace was lost, got:\n%s", repanicked.StackTrace())
+	}
+	if repanicked.Error() != "Oh no!" {
+		t.Errorf("Expected the error to read as the original value, got %q", repanicked.Error())
+	}
+}
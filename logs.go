@@ -0,0 +1,86 @@
+package sanepanic
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// How many lines written to the LogSink are attached to each panic.
+const recentLogLines = 20
+
+// A ring buffer of the last few lines written to it.
+type logSink struct {
+	mu      sync.Mutex
+	lines   [recentLogLines]string
+	start   int // Index of the oldest line
+	count   int
+	partial []byte // The start of a line that hasn't been finished yet
+}
+
+var (
+	activeLogSink atomic.Value // The *logSink, once LogSink has been called
+	logSinkOnce   sync.Once
+)
+
+// Returns a writer that remembers the last few lines written to it. Once you've plugged it into your logger
+// (e.g. with io.MultiWriter alongside your usual output) those lines are attached to every panic, see Info.RecentLogs,
+// giving some context as to what the program was doing before it panicked.
+func LogSink() io.Writer {
+	logSinkOnce.Do(func() {
+		activeLogSink.Store(&logSink{})
+	})
+	return currentLogSink()
+}
+
+func currentLogSink() *logSink {
+	sink, _ := activeLogSink.Load().(*logSink)
+	return sink
+}
+
+func (s *logSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	written := len(p)
+	for {
+		newline := bytes.IndexByte(p, '\n')
+		if newline < 0 {
+			s.partial = append(s.partial, p...)
+			return written, nil
+		}
+		s.add(string(append(s.partial, p[:newline]...)))
+		s.partial = s.partial[:0]
+		p = p[newline+1:]
+	}
+}
+
+// Expects s.mu to be held.
+func (s *logSink) add(line string) {
+	if s.count < len(s.lines) {
+		s.lines[(s.start+s.count)%len(s.lines)] = line
+		s.count++
+	} else {
+		s.lines[s.start] = line
+		s.start = (s.start + 1) % len(s.lines)
+	}
+}
+
+func (s *logSink) recent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	recent := make([]string, s.count)
+	for i := range recent {
+		recent[i] = s.lines[(s.start+i)%len(s.lines)]
+	}
+	return recent
+}
+
+// Returns the last lines written to the LogSink before the panic, oldest first.
+// It's empty if the LogSink isn't in use.
+func (info Info) RecentLogs() []string {
+	if info.logs == nil {
+		return nil
+	}
+	return append([]string(nil), (*info.logs)...)
+}
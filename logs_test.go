@@ -0,0 +1,33 @@
+package sanepanic_test
+
+import (
+	"fmt"
+	"github.com/Jragonmiris/sanepanic"
+	"log"
+	"testing"
+)
+
+func TestLogSink(t *testing.T) {
+	logger := log.New(sanepanic.LogSink(), "", 0)
+	for i := 0; i < 25; i++ {
+		logger.Printf("Line %d", i)
+	}
+	fmt.Fprint(sanepanic.LogSink(), "Unfinished line")
+
+	var logs []string
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		logs = info.RecentLogs()
+		return true
+	})
+	defer ph.Done()
+	ph.ForwardValueSync("Oh no!")
+
+	if len(logs) != 20 {
+		t.Fatalf("Expected the last 20 lines, got %d: %v", len(logs), logs)
+	}
+	for i, line := range logs {
+		if expected := fmt.Sprintf("Line %d", i+5); line != expected {
+			t.Errorf("Expected line %d to be %q, got %q", i, expected, line)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package sanepanic
+
+// A MetricsSink is notified of every panic a Handler receives, before the HandlerFunc runs. Implement it with
+// whatever metrics library you use (a Prometheus counter, for instance); sanepanic only defines the interface
+// so it doesn't drag any of them in as dependencies.
+//
+// PanicObserved is called from the listener, so it should be quick.
+type MetricsSink interface {
+	PanicObserved(Info)
+}
+
+// Sets the MetricsSink notified of every panic forwarded to the package's listener. Nil disables it.
+func SetMetricsSink(sink MetricsSink) {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.SetMetricsSink(sink)
+}
+
+// Sets the MetricsSink notified of every panic forwarded to this handler. Nil disables it.
+func (ph *Handler) SetMetricsSink(sink MetricsSink) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.metrics = sink
+}
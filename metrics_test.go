@@ -0,0 +1,39 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"testing"
+)
+
+type fakeSink struct {
+	observed []sanepanic.Info
+}
+
+func (s *fakeSink) PanicObserved(info sanepanic.Info) {
+	s.observed = append(s.observed, info)
+}
+
+func TestMetricsSink(t *testing.T) {
+	handled := []sanepanic.Info{}
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled = append(handled, info)
+		return true
+	})
+	defer ph.Done()
+
+	sink := &fakeSink{}
+	ph.SetMetricsSink(sink)
+
+	for i := 0; i < 3; i++ {
+		ph.ForwardValueSync(i)
+	}
+
+	if len(sink.observed) != 3 {
+		t.Fatalf("Expected the sink to observe 3 panics, observed %d", len(sink.observed))
+	}
+	for i, info := range sink.observed {
+		if info != handled[i] {
+			t.Errorf("Sink observed %v, but the handler received %v", info, handled[i])
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package sanepanic
+
+import (
+	"sync/atomic"
+)
+
+// Creates a new panic handler that delivers each panic on the returned channel rather than calling a HandlerFunc,
+// AND makes it start listening. This suits event loops that would rather select on panics alongside their other work.
+//
+// The channel is buffered to hold bufferSize panics. If it's full when a panic arrives the panic is dropped,
+// and counted in Dropped, rather than holding up the listener.
+func NewNotifyHandler(bufferSize int) (*Handler, <-chan Info) {
+	notify := make(chan Info, bufferSize)
+	ph := &Handler{}
+	ph.handle = func(info Info) bool {
+		select {
+		case notify <- info:
+		default:
+			atomic.AddUint64(&ph.dropped, 1)
+		}
+		return true
+	}
+	ph.start()
+	return ph, notify
+}
+
+// Returns how many panics a handler created with NewNotifyHandler has dropped because its channel was full.
+func (ph *Handler) Dropped() uint64 {
+	return atomic.LoadUint64(&ph.dropped)
+}
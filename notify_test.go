@@ -0,0 +1,29 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"testing"
+)
+
+func TestNotifyHandler(t *testing.T) {
+	ph, notify := sanepanic.NewNotifyHandler(3)
+	defer ph.Done()
+
+	for i := 0; i < 3; i++ {
+		ph.ForwardValueSync(i)
+	}
+	if dropped := ph.Dropped(); dropped != 0 {
+		t.Errorf("Expected no dropped panics, %d were dropped", dropped)
+	}
+
+	ph.ForwardValueSync(3)
+	if dropped := ph.Dropped(); dropped != 1 {
+		t.Errorf("Expected the panic to be dropped once the buffer is full, %d were dropped", dropped)
+	}
+
+	for i := 0; i < 3; i++ {
+		if info := <-notify; info.Info != i {
+			t.Errorf("Expected panic %d on the channel, got %v", i, info.Info)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package sanepanic
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A ColorMode controls whether DefaultHandlerFunc and VerboseDefaultHandlerFunc color their output.
+type ColorMode int
+
+const (
+	// Never color output. This is the default.
+	ColorNever ColorMode = iota
+	// Color output if it's going to a terminal.
+	ColorAuto
+	// Always color output.
+	ColorAlways
+)
+
+var (
+	output    io.Writer = os.Stderr
+	colorMode           = ColorNever
+	outputMu  sync.Mutex
+
+	verboseSequence uint64
+)
+
+// Sets where DefaultHandlerFunc and VerboseDefaultHandlerFunc print panics. The default is stderr.
+func SetOutput(w io.Writer) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	output = w
+}
+
+// Sets whether DefaultHandlerFunc and VerboseDefaultHandlerFunc print panics with a red header and dimmed stack trace.
+// With ColorAuto the output is only colored if the writer set with SetOutput is a terminal, so piped output stays plain.
+func SetColorOutput(mode ColorMode) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	colorMode = mode
+}
+
+// Prints the Info to the output after header, coloring it if the color mode calls for it.
+func writeInfo(header string, info Info) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	io.WriteString(output, header+formatInfo(info, useColor()))
+}
+
+// Reports a problem sanepanic itself ran into, such as failing to write a crash dump, to the output.
+func logf(format string, args ...interface{}) {
+	outputMu.Lock()
+	defer outputMu.Unlock()
+	fmt.Fprintf(output, "sanepanic: "+format+"\n", args...)
+}
+
+// Expects outputMu to be held.
+func useColor() bool {
+	switch colorMode {
+	case ColorAlways:
+		return true
+	case ColorAuto:
+		return isTerminal(output)
+	}
+	return false
+}
+
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := file.Stat()
+	return err == nil && stat.Mode()&os.ModeCharDevice != 0
+}
+
+// The same as DefaultHandlerFunc, except each panic is prefixed by an RFC3339 timestamp and a sequence number
+// that increases with every panic it prints. This makes panics easier to tell apart and order when
+// several end up in the same log.
+func VerboseDefaultHandlerFunc(info Info) bool {
+	sequence := atomic.AddUint64(&verboseSequence, 1)
+	when := info.Time
+	if when.IsZero() {
+		when = time.Now()
+	}
+	writeInfo(fmt.Sprintf("[%s #%d]\n", when.Format(time.RFC3339), sequence), info)
+	return true
+}
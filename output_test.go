@@ -0,0 +1,122 @@
+package sanepanic_test
+
+import (
+	"bytes"
+	"github.com/Jragonmiris/sanepanic"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestVerboseDefaultHandlerFunc(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	defer sanepanic.SetOutput(os.Stderr)
+
+	ph := sanepanic.NewHandler(sanepanic.VerboseDefaultHandlerFunc)
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			defer ph.ForwardAndDone(wg)
+			panic("Arghlbarg")
+		}()
+	}
+	wg.Wait()
+	ph.ForwardValueSync("Flush")
+	ph.Done()
+
+	headers := regexp.MustCompile(`(?m)^\[\S+ #(\d+)\]$`).FindAllStringSubmatch(buf.String(), -1)
+	if len(headers) != 6 {
+		t.Fatalf("Expected 6 panics to be printed, found %d in:\n%s", len(headers), buf)
+	}
+
+	last := uint64(0)
+	for _, header := range headers {
+		sequence, err := strconv.ParseUint(header[1], 10, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sequence <= last {
+			t.Errorf("Sequence number %d doesn't increase on %d", sequence, last)
+		}
+		last = sequence
+	}
+}
+
+func TestVerboseDefaultHandlerFuncConcurrent(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	defer sanepanic.SetOutput(os.Stderr)
+
+	wg := &sync.WaitGroup{}
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			sanepanic.VerboseDefaultHandlerFunc(sanepanic.Info{Info: "Arghlbarg"})
+		}()
+	}
+	wg.Wait()
+
+	seen := map[string]bool{}
+	for _, header := range regexp.MustCompile(`(?m)^\[\S+ #(\d+)\]$`).FindAllStringSubmatch(buf.String(), -1) {
+		if seen[header[1]] {
+			t.Errorf("Sequence number %s was used twice", header[1])
+		}
+		seen[header[1]] = true
+	}
+	if len(seen) != 10 {
+		t.Errorf("Expected 10 distinct sequence numbers, got %d", len(seen))
+	}
+}
+
+func TestNopHandlerFunc(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	defer sanepanic.SetOutput(os.Stderr)
+
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer ph.ForwardAndDone(wg)
+		panic("Oh no!")
+	}()
+	wg.Wait()
+	ph.Drain(5 * time.Second)
+	ph.Done()
+
+	if buf.Len() != 0 {
+		t.Errorf("NopHandlerFunc produced output:\n%s", buf)
+	}
+}
+
+func TestColorOutput(t *testing.T) {
+	defer sanepanic.SetColorOutput(sanepanic.ColorNever)
+	defer sanepanic.SetOutput(os.Stderr)
+
+	info := sanepanic.Info{Info: "Oh no!", StackTrace: "goroutine 7 [running]:\nmain.main()"}
+
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	sanepanic.SetColorOutput(sanepanic.ColorAuto)
+	sanepanic.DefaultHandlerFunc(info)
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("Output to a buffer was colored with ColorAuto:\n%q", buf)
+	}
+
+	buf.Reset()
+	sanepanic.SetColorOutput(sanepanic.ColorAlways)
+	sanepanic.DefaultHandlerFunc(info)
+	if !strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("Output wasn't colored with ColorAlways:\n%q", buf)
+	}
+	if !strings.Contains(buf.String(), "Oh no!") || !strings.Contains(buf.String(), "main.main()") {
+		t.Errorf("Colored output lost the panic details:\n%q", buf)
+	}
+}
@@ -0,0 +1,70 @@
+package sanepanic
+
+// Holds panics forwarded to the package's listener until Resume is called. See Handler.Pause.
+func Pause() {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.Pause()
+}
+
+// Handles any panics held since Pause was called, and goes back to handling panics as they arrive.
+func Resume() {
+	mu.Lock()
+	defer mu.Unlock()
+	internalPanicHandler.Resume()
+}
+
+// Makes the listener hold on to panics rather than handle them, until Resume is called. Forward doesn't block
+// while paused, but ForwardValueSync and the like still wait for their panic to be handled. Stopping the listener
+// with Done handles the held panics first.
+//
+// This is useful in critical sections (e.g. while already shutting down) where the app isn't ready to act on a panic
+// yet.
+func (ph *Handler) Pause() {
+	ph.pauseMu.Lock()
+	defer ph.pauseMu.Unlock()
+	ph.paused = true
+}
+
+// Handles any panics held since Pause was called, and goes back to handling panics as they arrive.
+func (ph *Handler) Resume() {
+	ph.start()
+	ph.pauseMu.Lock()
+	defer ph.pauseMu.Unlock()
+	ph.paused = false
+	select {
+	case ph.resumed <- struct{}{}:
+	default: // The listener has already been told
+	}
+}
+
+// Holds on to the panic if we're paused, returns whether it did.
+func (ph *Handler) hold(fp forwardedPanic) bool {
+	ph.pauseMu.Lock()
+	defer ph.pauseMu.Unlock()
+	if ph.paused {
+		ph.held = append(ph.held, fp)
+	}
+	return ph.paused
+}
+
+// Takes the held panics, unless we're still paused.
+func (ph *Handler) release() []forwardedPanic {
+	ph.pauseMu.Lock()
+	defer ph.pauseMu.Unlock()
+	if ph.paused {
+		return nil
+	}
+	held := ph.held
+	ph.held = nil
+	return held
+}
+
+// Takes the held panics whether or not we're paused.
+func (ph *Handler) releaseAll() []forwardedPanic {
+	ph.pauseMu.Lock()
+	defer ph.pauseMu.Unlock()
+	held := ph.held
+	ph.held = nil
+	return held
+}
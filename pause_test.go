@@ -0,0 +1,56 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPause(t *testing.T) {
+	mu := &sync.Mutex{}
+	handled := []interface{}{}
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		handled = append(handled, info.Info)
+		return true
+	})
+	defer ph.Done()
+
+	ph.Pause()
+	ph.ForwardValue(1)
+	ph.ForwardValue(2)
+	time.Sleep(20 * time.Millisecond)
+
+	mu.Lock()
+	if len(handled) != 0 {
+		t.Errorf("Panics were handled while paused: %v", handled)
+	}
+	mu.Unlock()
+
+	ph.Resume()
+	ph.ForwardValueSync(3) // Held panics are handled first
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 3 || handled[0] != 1 || handled[1] != 2 || handled[2] != 3 {
+		t.Errorf("Expected the held panics to be handled in order on resume, got %v", handled)
+	}
+}
+
+func TestPauseDone(t *testing.T) {
+	handled := 0
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		handled++
+		return true
+	})
+
+	ph.Pause()
+	ph.ForwardValue("Oh no!")
+	ph.Done()
+
+	if handled != 1 {
+		t.Errorf("Expected Done to handle the held panic, %d were handled", handled)
+	}
+}
@@ -0,0 +1,76 @@
+package sanepanic
+
+// Runs body, forwarding any panic to the package's listener and then returning normally. Unlike Forward, which is
+// meant for goroutines that end when they panic, this is meant for loops that should carry on with the next
+// iteration:
+//
+//	for job := range jobs {
+//		sanepanic.Protect(func() { process(job) })
+//	}
+func Protect(body func()) {
+	defer func() {
+		err := recover() // Have to do recover directly in deferred function
+		packageHandler().forward(err)
+	}()
+	body()
+}
+
+// As with the package level function, runs body and forwards any panic to this handler before returning normally.
+func (ph *Handler) Protect(body func()) {
+	defer func() {
+		ph.forward(recover())
+	}()
+	body()
+}
+
+// Forwards a recovered value to the package's listener, waits for it to be handled, and returns the Info that was
+// captured. The bool is false, and nothing is forwarded, if err is nil (i.e. there was no panic).
+// Since recover only works when called directly by a deferred function, pass it the result of recover:
+//
+//	defer func() {
+//		if info, ok := sanepanic.ForwardReturning(recover()); ok {
+//			// Clean up, or re-panic, depending on info
+//		}
+//	}()
+func ForwardReturning(err interface{}) (Info, bool) {
+	ph := packageHandler()
+	return ph.ForwardReturning(err)
+}
+
+// As with the package level function, forwards a recovered value to this handler and returns the captured Info once
+// it's been handled.
+func (ph *Handler) ForwardReturning(err interface{}) (Info, bool) {
+	if err == nil {
+		return Info{}, false
+	}
+	if ph.nopMode() {
+		return Info{Info: err}, true
+	}
+	info := ph.newInfo(err, 0)
+	done := make(chan struct{})
+	if ph.send(forwardedPanic{info: info, done: done}) {
+		<-done
+	}
+	return info, true
+}
+
+// Runs f and reports whether it panicked. Any panic is forwarded to the package's listener, and WithRecover waits for
+// it to be handled before returning, so it can go straight into an if statement:
+//
+//	if sanepanic.WithRecover(doThing) {
+//		cleanup()
+//	}
+func WithRecover(f func()) (recovered bool) {
+	ph := packageHandler()
+	return ph.WithRecover(f)
+}
+
+// As with the package level function, runs f and reports whether it panicked, forwarding the panic to this handler and
+// waiting for it to be handled.
+func (ph *Handler) WithRecover(f func()) (recovered bool) {
+	defer func() {
+		_, recovered = ph.ForwardReturning(recover())
+	}()
+	f()
+	return false
+}
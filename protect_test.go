@@ -0,0 +1,112 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"strings"
+	"testing"
+)
+
+func TestProtect(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	defer ph.Done()
+
+	iterations := 0
+	for i := 0; i < 5; i++ {
+		ph.Protect(func() {
+			if i == 2 {
+				panic(i)
+			}
+			iterations++
+		})
+	}
+
+	if iterations != 4 {
+		t.Errorf("Expected the 4 iterations that didn't panic to run, %d ran", iterations)
+	}
+
+	info := <-handled
+	if info.Info != 2 {
+		t.Errorf("Expected the panic from iteration 2, got %v", info.Info)
+	}
+	if !strings.Contains(info.Function, "TestProtect") {
+		t.Errorf("Expected the panic to originate in TestProtect, got %q", info.Function)
+	}
+}
+
+func TestForwardReturning(t *testing.T) {
+	handled := false
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		handled = true
+		return true
+	})
+	defer ph.Done()
+
+	var info sanepanic.Info
+	var ok bool
+	func() {
+		defer func() {
+			info, ok = ph.ForwardReturning(recover())
+		}()
+		panicInNamedFunction()
+	}()
+
+	if !ok {
+		t.Fatalf("ForwardReturning didn't report a panic")
+	}
+	if !handled {
+		t.Errorf("ForwardReturning returned before the panic was handled")
+	}
+	if info.Info != "Oh no!" {
+		t.Errorf("Expected the returned Info to hold \"Oh no!\", got %v", info.Info)
+	}
+	if !strings.HasSuffix(info.Function, ".panicInNamedFunction") {
+		t.Errorf("Expected the panic to originate in panicInNamedFunction, got %q", info.Function)
+	}
+}
+
+func TestForwardReturningNoPanic(t *testing.T) {
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		t.Errorf("Handler ran without a panic")
+		return true
+	})
+	defer ph.Done()
+
+	func() {
+		defer func() {
+			if _, ok := ph.ForwardReturning(recover()); ok {
+				t.Errorf("ForwardReturning reported a panic that didn't happen")
+			}
+		}()
+	}()
+}
+
+func TestWithRecover(t *testing.T) {
+	handled := []interface{}{}
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled = append(handled, info.Info)
+		return true
+	})
+	defer ph.Done()
+
+	if !ph.WithRecover(func() { panic("Oh no!") }) {
+		t.Errorf("WithRecover didn't report the panic")
+	}
+	if len(handled) != 1 || handled[0] != "Oh no!" {
+		t.Errorf("Expected the handler to have seen the panic by the time WithRecover returned, saw %v", handled)
+	}
+
+	ran := false
+	if ph.WithRecover(func() { ran = true }) {
+		t.Errorf("WithRecover reported a panic when there wasn't one")
+	}
+	if !ran {
+		t.Errorf("WithRecover didn't run the function")
+	}
+	if len(handled) != 1 {
+		t.Errorf("Expected nothing more to be handled without a panic, saw %v", handled)
+	}
+}
@@ -0,0 +1,97 @@
+package sanepanic
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// A Reporter sends panics to an external crash reporting service (Sentry, an HTTP endpoint and so on).
+// Implement it for your backend and install it with ReporterHandlerFunc; sanepanic has no dependencies
+// on any particular transport.
+type Reporter interface {
+	Report(context.Context, Info) error
+}
+
+const (
+	defaultReportRetries = 3
+	defaultReportTimeout = 10 * time.Second
+	reportRetryDelay     = 50 * time.Millisecond
+)
+
+// ReporterHandlerFunc returns a HandlerFunc that sends each panic to r, retrying up to 3 times with each attempt
+// limited to 10 seconds. See ReporterHandlerFuncWithRetries.
+func ReporterHandlerFunc(r Reporter) HandlerFunc {
+	return ReporterHandlerFuncWithRetries(r, defaultReportRetries, defaultReportTimeout)
+}
+
+// ReporterHandlerFuncWithRetries returns a HandlerFunc that sends each panic to r. Each attempt gets a context that's
+// cancelled after timeout (derived from the context the panic was forwarded with, if any), and failures are
+// assumed to be transient and retried up to retries times, waiting a little longer before each retry. Errors marked
+// with Permanent, or that report themselves as not Temporary, aren't retried, and neither is anything once the context
+// the panic was forwarded with is done. A negative retries is treated as 0.
+// If every attempt fails the last error is logged. Either way the handler keeps running.
+func ReporterHandlerFuncWithRetries(r Reporter, retries int, timeout time.Duration) HandlerFunc {
+	if retries < 0 {
+		retries = 0
+	}
+	return func(info Info) bool {
+		parent := info.Context
+		if parent == nil {
+			parent = context.Background()
+		}
+
+		var err error
+		attempts := 0
+		for attempts <= retries {
+			if attempts > 0 {
+				time.Sleep(time.Duration(attempts) * reportRetryDelay)
+			}
+
+			ctx, cancel := context.WithTimeout(parent, timeout)
+			err = r.Report(ctx, info)
+			cancel()
+			attempts++
+			if err == nil {
+				return true
+			}
+			if isPermanent(err) || parent.Err() != nil {
+				break
+			}
+		}
+
+		logf("could not report panic after %d attempts: %v", attempts, err)
+		return true
+	}
+}
+
+// Permanent marks err as one that retrying won't fix, such as the service rejecting the report outright, so that
+// ReporterHandlerFuncWithRetries gives up straight away. The wrapped error is still visible to errors.Is and
+// errors.As. Permanent(nil) is nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+func isPermanent(err error) bool {
+	var permanent *permanentError
+	if errors.As(err, &permanent) {
+		return true
+	}
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && !temporary.Temporary()
+}
@@ -0,0 +1,102 @@
+package sanepanic_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/Jragonmiris/sanepanic"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flakyReporter struct {
+	failures  int
+	attempts  int
+	reported  []sanepanic.Info
+	permanent bool
+}
+
+func (r *flakyReporter) Report(ctx context.Context, info sanepanic.Info) error {
+	r.attempts++
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("no deadline")
+	}
+	if r.attempts <= r.failures {
+		if r.permanent {
+			return sanepanic.Permanent(errors.New("report rejected"))
+		}
+		return errors.New("service unavailable")
+	}
+	r.reported = append(r.reported, info)
+	return nil
+}
+
+func TestReporterHandlerFunc(t *testing.T) {
+	reporter := &flakyReporter{failures: 2}
+	handler := sanepanic.ReporterHandlerFunc(reporter)
+
+	if !handler(sanepanic.Info{Info: "Oh no!"}) {
+		t.Errorf("Reporter handler asked to stop handling")
+	}
+	if reporter.attempts != 3 {
+		t.Errorf("Expected 2 failures followed by a success, made %d attempts", reporter.attempts)
+	}
+	if len(reporter.reported) != 1 || reporter.reported[0].Info != "Oh no!" {
+		t.Errorf("Expected the panic to be reported once, reported %v", reporter.reported)
+	}
+}
+
+func TestReporterHandlerFuncGivesUp(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	defer sanepanic.SetOutput(os.Stderr)
+
+	reporter := &flakyReporter{failures: 10}
+	handler := sanepanic.ReporterHandlerFuncWithRetries(reporter, 1, time.Second)
+
+	if !handler(sanepanic.Info{Info: "Oh no!"}) {
+		t.Errorf("Reporter handler asked to stop handling after failing")
+	}
+	if reporter.attempts != 2 {
+		t.Errorf("Expected 2 attempts with 1 retry, made %d", reporter.attempts)
+	}
+	if !strings.Contains(buf.String(), "service unavailable") {
+		t.Errorf("Permanent failure wasn't logged, output was:\n%s", buf)
+	}
+}
+
+func TestReporterHandlerFuncPermanentError(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	defer sanepanic.SetOutput(os.Stderr)
+
+	reporter := &flakyReporter{failures: 10, permanent: true}
+	handler := sanepanic.ReporterHandlerFuncWithRetries(reporter, 3, time.Second)
+
+	handler(sanepanic.Info{Info: "Oh no!"})
+	if reporter.attempts != 1 {
+		t.Errorf("Expected a permanent error not to be retried, made %d attempts", reporter.attempts)
+	}
+	if !strings.Contains(buf.String(), "after 1 attempts: report rejected") {
+		t.Errorf("Permanent error wasn't logged, output was:\n%s", buf)
+	}
+}
+
+func TestReporterHandlerFuncNegativeRetries(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	defer sanepanic.SetOutput(os.Stderr)
+
+	reporter := &flakyReporter{failures: 10}
+	handler := sanepanic.ReporterHandlerFuncWithRetries(reporter, -1, time.Second)
+
+	handler(sanepanic.Info{Info: "Oh no!"})
+	if reporter.attempts != 1 {
+		t.Errorf("Expected a negative retries to make a single attempt, made %d", reporter.attempts)
+	}
+	if !strings.Contains(buf.String(), "service unavailable") {
+		t.Errorf("Failure wasn't logged, output was:\n%s", buf)
+	}
+}
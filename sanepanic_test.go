@@ -1,9 +1,13 @@
 package sanepanic_test
 
 import (
+	"bytes"
 	"github.com/Jragonmiris/sanepanic"
+	"os"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestBasic(t *testing.T) {
@@ -133,3 +137,392 @@ func TestNested(t *testing.T) {
 
 	wg.Wait() // Will deadlock if test fails
 }
+
+func TestForwardValueSync(t *testing.T) {
+	mu := &sync.Mutex{}
+	finished := false
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		if info.Info != "Oh no!" {
+			t.Errorf("Unexpected panic value %v", info.Info)
+		}
+		finished = true
+		return true
+	})
+	defer ph.Done()
+
+	ph.ForwardValueSync("Oh no!")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !finished {
+		t.Errorf("ForwardValueSync returned before the handler finished")
+	}
+}
+
+func TestFallbackHandlerFunc(t *testing.T) {
+	primary := 0
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		primary++
+		return false
+	})
+	defer ph.Done()
+
+	var fallbackInfo interface{}
+	ph.SetFallbackHandlerFunc(func(info sanepanic.Info) bool {
+		fallbackInfo = info.Info
+		return true
+	})
+
+	ph.ForwardValueSync("first")
+	ph.ForwardValueSync("second")
+
+	if primary != 1 {
+		t.Errorf("Expected the primary handler to run once, ran %d times", primary)
+	}
+	if fallbackInfo != "second" {
+		t.Errorf("Fallback handler didn't receive the panic after the primary stopped, got %v", fallbackInfo)
+	}
+}
+
+func TestForwardAndDone(t *testing.T) {
+	mu := &sync.Mutex{}
+	i := 0
+	wg := &sync.WaitGroup{}
+
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		blankInfo := sanepanic.Info{}
+		if info == blankInfo {
+			t.Errorf("No panic info exists")
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		i++
+
+		return true
+	})
+
+	wg.Add(5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			defer ph.ForwardAndDone(wg)
+			panic("Arghlbarg")
+		}()
+	}
+
+	wg.Wait()
+	ph.ForwardValueSync("Flush") // Everything before this has been fully handled once it returns
+	ph.Done()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if i != 6 {
+		t.Errorf("Expected 6 handled panics, got %d", i)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	var handled sanepanic.Info
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled = info
+		return true
+	})
+	defer ph.Done()
+
+	ph.SetRedactor(func(info sanepanic.Info) sanepanic.Info {
+		info.Info = "[redacted]"
+		return info
+	})
+
+	ph.ForwardValueSync("password: hunter2")
+
+	if handled.Info != "[redacted]" {
+		t.Errorf("Handler observed the unredacted value %v", handled.Info)
+	}
+	if handled.StackTrace == "" {
+		t.Errorf("Redactor lost the stack trace")
+	}
+}
+
+func TestWrapHandlerFunc(t *testing.T) {
+	original := 0
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		original++
+		return true
+	})
+	defer ph.Done()
+
+	wrapped := 0
+	inner := ph.HandlerFunc()
+	ph.SetHandlerFunc(func(info sanepanic.Info) bool {
+		wrapped++
+		return inner(info)
+	})
+
+	ph.ForwardValueSync("Oh no!")
+
+	if wrapped != 1 || original != 1 {
+		t.Errorf("Expected both the wrapper and original handler to run once, ran %d and %d times", wrapped, original)
+	}
+}
+
+func TestCurrentHandlerFunc(t *testing.T) {
+	previous := sanepanic.CurrentHandlerFunc()
+	defer sanepanic.SetHandlerFunc(previous)
+
+	called := false
+	sanepanic.SetHandlerFunc(func(sanepanic.Info) bool {
+		called = true
+		return true
+	})
+
+	sanepanic.CurrentHandlerFunc()(sanepanic.Info{Info: "Oh no!"})
+	if !called {
+		t.Errorf("CurrentHandlerFunc didn't return the installed HandlerFunc")
+	}
+}
+
+type tracedError struct {
+	stack string
+}
+
+func (e tracedError) Error() string {
+	return "traced error"
+}
+
+func (e tracedError) StackTrace() string {
+	return e.stack
+}
+
+func TestPreservedStackTrace(t *testing.T) {
+	handled := sanepanic.Info{}
+	ph := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled = info
+		return true
+	})
+	defer ph.Done()
+
+	traced := "goroutine 7 [running]:\noriginal.origin()\n\t/src/original.go:10 +0x1d\n"
+	ph.ForwardValueSync(tracedError{stack: traced})
+	if handled.StackTrace != traced {
+		t.Errorf("Stack trace carried by the panic value was replaced with:\n%s", handled.StackTrace)
+	}
+	if handled.Function != "original.origin" || handled.GoroutineID != 7 {
+		t.Errorf("Expected the Info to describe the traced origin, got function %q in goroutine %d",
+			handled.Function, handled.GoroutineID)
+	}
+
+	original := sanepanic.Info{Info: "Oh no!", StackTrace: "goroutine 7 [running]:\noriginal.origin()", GoroutineID: 7}
+	ph.ForwardValueSync(original)
+	if handled != original {
+		t.Errorf("Re-forwarded Info %v was replaced with %v", original, handled)
+	}
+
+	ph.ForwardValueSync(&original)
+	if handled != original {
+		t.Errorf("Re-forwarded *Info %v was replaced with %v", original, handled)
+	}
+
+	ph.ForwardValueSync((*sanepanic.Info)(nil))
+	if handled.Info != (*sanepanic.Info)(nil) {
+		t.Errorf("Expected a nil *Info to be forwarded like any other value, got %v", handled.Info)
+	}
+}
+
+func TestZeroHandler(t *testing.T) {
+	ph := &sanepanic.Handler{}
+	defer ph.Done()
+
+	handled := false
+	ph.SetHandlerFunc(func(sanepanic.Info) bool {
+		handled = true
+		return true
+	})
+	ph.ForwardValueSync("Oh no!")
+
+	if !handled {
+		t.Errorf("Zero Handler didn't handle the panic")
+	}
+}
+
+func TestZeroHandlerDone(t *testing.T) {
+	ph := &sanepanic.Handler{}
+	ph.Done()
+	ph.Done()
+	ph.ForwardValueSync("Oh no!") // Passes silently since the handler has stopped
+}
+
+func TestReentrantPanic(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	defer sanepanic.SetOutput(os.Stderr)
+
+	var ph *sanepanic.Handler
+	ph = sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		if info.Info == "Oh no!" {
+			// Would deadlock if it waited on the listener, which is busy running this
+			ph.ForwardValueSync("Oh no, again!")
+			func() {
+				defer ph.Forward()
+				panic("Oh no, a third time!")
+			}()
+		}
+		return true
+	})
+	defer ph.Done()
+
+	ph.ForwardValueSync("Oh no!")
+
+	if !strings.Contains(buf.String(), "Oh no, again!") || !strings.Contains(buf.String(), "Oh no, a third time!") {
+		t.Errorf("Re-entrant panics weren't reported, output was:\n%s", buf)
+	}
+}
+
+func TestReentrantPanicPackageLevel(t *testing.T) {
+	defer sanepanic.SaveGlobalState()()
+	sanepanic.Restart() // Earlier tests may have left the listener stopped
+	buf := &syncBuffer{}
+	sanepanic.SetOutput(buf)
+
+	entered, finished := make(chan struct{}), make(chan struct{})
+	sanepanic.SetHandlerFunc(func(info sanepanic.Info) bool {
+		switch info.Info {
+		case "Oh no!":
+			close(entered)
+			time.Sleep(50 * time.Millisecond) // Let the other goroutine block forwarding to us
+			sanepanic.ForwardValue("Oh no, again!")
+		case "Blocked":
+			close(finished)
+		}
+		return true
+	})
+
+	sanepanic.ForwardValue("Oh no!")
+	<-entered
+	go func() {
+		defer sanepanic.Forward()
+		panic("Blocked")
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Forwarding from a HandlerFunc deadlocked with a blocked forwarder")
+	}
+	if !strings.Contains(buf.String(), "Oh no, again!") {
+		t.Errorf("Re-entrant panic wasn't reported, output was:\n%s", buf)
+	}
+}
+
+// A bytes.Buffer that's safe to read while the listener might be writing to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestDoneFromHandlerFunc(t *testing.T) {
+	var ph *sanepanic.Handler
+	ph = sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		ph.Done()
+		return true
+	})
+
+	ph.ForwardValueSync("Oh no!")
+	ph.Done() // Waits for the listener to stop
+}
+
+func TestForwardTo(t *testing.T) {
+	first, second := make(chan sanepanic.Info, 1), make(chan sanepanic.Info, 1)
+	ph1 := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		first <- info
+		return true
+	})
+	defer ph1.Done()
+	ph2 := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		second <- info
+		return true
+	})
+	defer ph2.Done()
+
+	go func() {
+		defer sanepanic.ForwardTo(ph1, ph2)
+		panic("Oh no!")
+	}()
+
+	info1, info2 := <-first, <-second
+	if info1.Info != "Oh no!" {
+		t.Errorf("First handler got unexpected panic value %v", info1.Info)
+	}
+	if info1 != info2 {
+		t.Errorf("Handlers received different Infos for the same panic")
+	}
+}
+
+func TestSwapHandlerFunc(t *testing.T) {
+	const total = 200
+
+	mu := &sync.Mutex{}
+	seen := map[interface{}]int{}
+	handledBy := map[string]int{}
+	counting := func(name string) sanepanic.HandlerFunc {
+		return func(info sanepanic.Info) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[info.Info]++
+			handledBy[name]++
+			return true
+		}
+	}
+	ph := sanepanic.NewHandler(counting("a"))
+	defer ph.Done()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(total)
+	for i := 0; i < total; i++ {
+		go func(i int) {
+			defer ph.ForwardAndDone(wg)
+			panic(i)
+		}(i)
+	}
+
+	// Keep swapping between two HandlerFuncs while the panics stream in
+	for i := 0; i < 100; i++ {
+		name := "a"
+		if i%2 == 0 {
+			name = "b"
+		}
+		if old := ph.SwapHandlerFunc(counting(name)); old == nil {
+			t.Fatalf("SwapHandlerFunc returned a nil HandlerFunc")
+		}
+	}
+	wg.Wait()
+	if !ph.Drain(5 * time.Second) {
+		t.Fatalf("Drain timed out")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 0; i < total; i++ {
+		if seen[i] != 1 {
+			t.Errorf("Panic %d was handled %d times", i, seen[i])
+		}
+	}
+	if handledBy["a"]+handledBy["b"] != total {
+		t.Errorf("Expected %d panics handled between the two HandlerFuncs, got %v", total, handledBy)
+	}
+}
@@ -0,0 +1,54 @@
+package sanepanic
+
+import (
+	"errors"
+	"time"
+)
+
+// How long SelfTest waits for the listener to answer.
+const selfTestTimeout = time.Second
+
+// Checks that the package's listener is working. See Handler.SelfTest.
+func SelfTest() error {
+	ph := packageHandler()
+	return ph.SelfTest()
+}
+
+// Checks that forwarding to this handler works end to end, e.g. when a service starts, by sending a probe through the
+// same channel panics take and waiting up to a second for the listener to answer it. The probe isn't a real panic:
+// the listener answers it as soon as it's received (even while paused), without running the HandlerFunc or touching
+// the metrics sink, history, waiters or anything else that counts panics. An error is returned if the listener has
+// stopped, or doesn't answer in time because it's stuck handling something else.
+func (ph *Handler) SelfTest() error {
+	ph.start()
+	if ph.onListener() {
+		return errors.New("sanepanic: self test run from the listener, which can't answer itself")
+	}
+
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		if !ph.send(forwardedPanic{done: done, probe: true}) {
+			close(stopped)
+		}
+	}()
+
+	timeout := time.NewTimer(selfTestTimeout)
+	defer timeout.Stop()
+	select {
+	case <-done:
+		return nil
+	case <-stopped:
+		return errors.New("sanepanic: self test failed, the listener has stopped")
+	case <-timeout.C:
+		return errors.New("sanepanic: self test failed, the listener didn't respond")
+	}
+}
+
+// Answers a probe sent by SelfTest, returns false if fp is a real panic.
+func (ph *Handler) answerProbe(fp forwardedPanic) bool {
+	if !fp.probe {
+		return false
+	}
+	ph.settle(fp)
+	return true
+}
@@ -0,0 +1,36 @@
+package sanepanic_test
+
+import (
+	"github.com/Jragonmiris/sanepanic"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	handled := 0
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		handled++
+		return true
+	})
+	ph.SetHistorySize(10)
+
+	if err := ph.SelfTest(); err != nil {
+		t.Errorf("Self test failed on a healthy handler: %v", err)
+	}
+	ph.Pause()
+	if err := ph.SelfTest(); err != nil {
+		t.Errorf("Self test failed on a paused handler: %v", err)
+	}
+	ph.Resume()
+
+	if handled != 0 {
+		t.Errorf("Self test ran the HandlerFunc")
+	}
+	if recent := ph.RecentPanics(); len(recent) != 0 {
+		t.Errorf("Self test was recorded in the history: %v", recent)
+	}
+
+	ph.Done()
+	if err := ph.SelfTest(); err == nil {
+		t.Errorf("Self test passed after the listener was stopped")
+	}
+}
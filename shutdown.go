@@ -0,0 +1,47 @@
+package sanepanic
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Blocks until one of signals arrives, then drains and stops the package's listener. See Handler.HandleShutdownSignals.
+func HandleShutdownSignals(ctx context.Context, timeout time.Duration, signals ...os.Signal) {
+	if !waitForSignal(ctx, signals) {
+		return
+	}
+	packageHandler().Drain(timeout)
+	Done()
+}
+
+// Blocks until one of signals arrives (SIGINT or SIGTERM if none are given), then gives any panics that are still
+// being handled up to timeout to finish before stopping the listener with Done. This way a crash report that's
+// halfway written when the process is told to shut down isn't lost. If ctx is done first it returns without doing
+// anything. Run it in its own goroutine, or at the end of main; once it returns the process can exit.
+func (ph *Handler) HandleShutdownSignals(ctx context.Context, timeout time.Duration, signals ...os.Signal) {
+	if !waitForSignal(ctx, signals) {
+		return
+	}
+	ph.Drain(timeout)
+	ph.Done()
+}
+
+// Returns true if one of signals arrived, false if ctx was done first.
+func waitForSignal(ctx context.Context, signals []os.Signal) bool {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, signals...)
+	defer signal.Stop(received)
+
+	select {
+	case <-received:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,117 @@
+//go:build !windows
+
+package sanepanic_test
+
+import (
+	"context"
+	"github.com/Jragonmiris/sanepanic"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHandleShutdownSignals(t *testing.T) {
+	// Keep SIGUSR1 from killing the test binary whether or not the handler is listening for it yet
+	ignored := make(chan os.Signal, 1)
+	signal.Notify(ignored, syscall.SIGUSR1)
+	defer signal.Stop(ignored)
+
+	var handled int32
+	started := make(chan struct{})
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		atomic.StoreInt32(&handled, 1)
+		return true
+	})
+	defer ph.Done()
+
+	ph.ForwardValue("Oh no!")
+	<-started
+
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		ph.HandleShutdownSignals(context.Background(), 5*time.Second, syscall.SIGUSR1)
+	}()
+
+	// Keep signalling until the handler has noticed
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+			continue
+		case <-shutdown:
+		}
+		break
+	}
+
+	if atomic.LoadInt32(&handled) != 1 {
+		t.Errorf("Shut down before the panic being handled was finished")
+	}
+}
+
+func TestHandleShutdownSignalsTimeout(t *testing.T) {
+	ignored := make(chan os.Signal, 1)
+	signal.Notify(ignored, syscall.SIGUSR1)
+	defer signal.Stop(ignored)
+
+	var handled int32
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		time.Sleep(300 * time.Millisecond)
+		atomic.AddInt32(&handled, 1)
+		return true
+	})
+	defer ph.Done()
+
+	ph.ForwardValue("Oh no!")
+	go ph.ForwardValue("Never handled")
+	time.Sleep(20 * time.Millisecond)
+
+	shutdown := make(chan struct{})
+	go func() {
+		defer close(shutdown)
+		ph.HandleShutdownSignals(context.Background(), 10*time.Millisecond, syscall.SIGUSR1)
+	}()
+	tick := time.NewTicker(10 * time.Millisecond)
+	defer tick.Stop()
+	for {
+		select {
+		case <-tick.C:
+			syscall.Kill(os.Getpid(), syscall.SIGUSR1)
+			continue
+		case <-shutdown:
+		}
+		break
+	}
+
+	if n := atomic.LoadInt32(&handled); n != 1 {
+		t.Errorf("Expected only the panic being handled when the drain timed out to finish, %d were handled", n)
+	}
+}
+
+func TestHandleShutdownSignalsCancelled(t *testing.T) {
+	ph := sanepanic.NewHandler(sanepanic.NopHandlerFunc)
+	defer ph.Done()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ph.HandleShutdownSignals(ctx, 5*time.Second, syscall.SIGUSR1)
+
+	handled := make(chan struct{})
+	ph.SetHandlerFunc(func(sanepanic.Info) bool {
+		close(handled)
+		return true
+	})
+	ph.ForwardValue("Oh no!")
+	select {
+	case <-handled:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Listener was stopped even though the context was cancelled")
+	}
+}
@@ -0,0 +1,148 @@
+package sanepanic
+
+import (
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// The configurable parts of a Handler, as opposed to its channels and listener.
+type handlerConfig struct {
+	handle   HandlerFunc
+	batch    func([]Info) bool
+	fallback HandlerFunc
+	metrics  MetricsSink
+	chain    []chainedHandlerFunc
+	flushers []Flusher
+	redact   func(Info) Info
+	first    *sync.Once
+	onFirst  func(Info)
+	breaker  circuitBreaker
+	history  int
+	capture  captureOptions
+	nop      bool
+}
+
+func (ph *Handler) config() handlerConfig {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	return handlerConfig{
+		handle:   ph.handle,
+		batch:    ph.batch,
+		fallback: ph.fallback,
+		metrics:  ph.metrics,
+		chain:    append([]chainedHandlerFunc(nil), ph.chain...),
+		flushers: append([]Flusher(nil), ph.flushers...),
+		redact:   ph.redact,
+		first:    ph.firstPanicOnce(),
+		onFirst:  ph.onFirst,
+		breaker:  circuitBreaker{threshold: ph.breaker.threshold, window: ph.breaker.window, onOpen: ph.breaker.onOpen},
+		history:  ph.history.size(),
+		capture:  ph.captureOptions(),
+		nop:      ph.nopMode(),
+	}
+}
+
+func (ph *Handler) setConfig(config handlerConfig) {
+	ph.mu.Lock()
+	defer ph.mu.Unlock()
+	ph.handle = config.handle
+	ph.batch = config.batch
+	ph.fallback = config.fallback
+	ph.metrics = config.metrics
+	ph.chain = append([]chainedHandlerFunc(nil), config.chain...)
+	ph.flushers = append([]Flusher(nil), config.flushers...)
+	ph.redact = config.redact
+	ph.firstPanic = config.first
+	ph.onFirst = config.onFirst
+	ph.breaker = config.breaker
+	ph.history.setSize(config.history)
+	ph.SetNopMode(config.nop)
+
+	ph.captureMu.Lock()
+	defer ph.captureMu.Unlock()
+	ph.capture = config.capture
+}
+
+// Creates a new handler with the same HandlerFunc and settings as this one (registered HandlerFuncs, fallback,
+// metrics sink, flushers, redactor, first panic hook, circuit breaker, history size, stack settings and nop mode)
+// AND makes it start listening. The clone has its own listener, history and (closed) circuit breaker, so stopping
+// or tripping one handler doesn't affect the other, and its first panic hook runs on the first panic it handles.
+func (ph *Handler) Clone() *Handler {
+	config := ph.config()
+	config.first = nil // The clone hasn't handled a panic yet
+	return ph.cloneWith(config)
+}
+
+func (ph *Handler) cloneWith(config handlerConfig) *Handler {
+	clone := &Handler{listenWith: ph.listenWith}
+	clone.setConfig(config)
+	clone.start()
+	return clone
+}
+
+// The package level settings that aren't part of the handler.
+type globalState struct {
+	handler            handlerConfig
+	maxAutoRestarts    int
+	autoRestartBackoff time.Duration
+	output             io.Writer
+	colorMode          ColorMode
+	baseline           map[uint64]bool
+}
+
+// Captures the package's current configuration (the handler's settings along with the auto restart policy, output,
+// color mode and goroutine baseline) and returns a function that reinstalls it. This makes it easy for tests, or libraries that temporarily
+// change the handling, to put things back the way they were:
+//
+//	defer sanepanic.SaveGlobalState()()
+//
+// Restoring doesn't restart a stopped listener.
+func SaveGlobalState() func() {
+	mu.Lock()
+	defer mu.Unlock()
+	saved := globalState{
+		maxAutoRestarts:    maxAutoRestarts,
+		autoRestartBackoff: autoRestartBackoff,
+		baseline:           goroutineBaseline(),
+	}
+	// The listener takes outputMu while holding the handler's lock (DefaultHandlerFunc, logf), so outputMu has to be
+	// released before config takes the handler's lock, or the two could deadlock
+	outputMu.Lock()
+	saved.output, saved.colorMode = output, colorMode
+	outputMu.Unlock()
+	saved.handler = internalPanicHandler.config()
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		internalPanicHandler.setConfig(saved.handler)
+		maxAutoRestarts = saved.maxAutoRestarts
+		autoRestartBackoff = saved.autoRestartBackoff
+		watchForStop()
+		SetOutput(saved.output)
+		SetColorOutput(saved.colorMode)
+		baseline.Store(saved.baseline)
+	}
+}
+
+// Puts the package back the way it was when it was imported: a fresh listener running DefaultHandlerFunc with no
+// registered HandlerFuncs, fallback, metrics sink, flushers, redactor or history, automatic restarting off,
+// uncolored output to stderr and no goroutine baseline. This is mostly meant for keeping tests from leaking their
+// handling into each other.
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	maxAutoRestarts = 0
+	autoRestartBackoff = 0
+	autoRestarts = 0
+	stopWatchingForStop()
+
+	internalPanicHandler.Done()
+	internalPanicHandler = NewHandler(DefaultHandlerFunc)
+
+	SetOutput(os.Stderr)
+	SetColorOutput(ColorNever)
+	baseline.Store(map[uint64]bool(nil))
+}
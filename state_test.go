@@ -0,0 +1,142 @@
+package sanepanic_test
+
+import (
+	"bytes"
+	"github.com/Jragonmiris/sanepanic"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSaveGlobalState(t *testing.T) {
+	defer sanepanic.SaveGlobalState()()
+
+	original := ""
+	sanepanic.SetHandlerFunc(func(sanepanic.Info) bool {
+		original = "original"
+		return true
+	})
+	outer := &bytes.Buffer{}
+	sanepanic.SetOutput(outer)
+
+	func() {
+		defer sanepanic.SaveGlobalState()()
+
+		sanepanic.SetHandlerFunc(func(sanepanic.Info) bool {
+			t.Errorf("Scoped handler ran after the state was restored")
+			return true
+		})
+		sanepanic.AddHandlerFunc(func(sanepanic.Info) bool {
+			t.Errorf("Scoped registered handler ran after the state was restored")
+			return true
+		})
+		sanepanic.SetOutput(&bytes.Buffer{})
+	}()
+
+	sanepanic.Restart() // Earlier tests may have left the listener stopped
+	sanepanic.ForwardValueSync("Oh no!")
+	if original != "original" {
+		t.Errorf("Original handler wasn't restored")
+	}
+
+	sanepanic.DefaultHandlerFunc(sanepanic.Info{Info: "Oh no!"})
+	if outer.Len() == 0 {
+		t.Errorf("Original output wasn't restored")
+	}
+}
+
+func TestSaveGlobalStateWhileWriting(t *testing.T) {
+	defer sanepanic.SaveGlobalState()()
+	sanepanic.SetOutput(&bytes.Buffer{})
+
+	entered := make(chan struct{})
+	sanepanic.SetHandlerFunc(func(info sanepanic.Info) bool {
+		close(entered)
+		time.Sleep(50 * time.Millisecond) // Give SaveGlobalState time to get in before the output is written
+		return sanepanic.DefaultHandlerFunc(info)
+	})
+	sanepanic.ForwardValue("Oh no!")
+	<-entered
+
+	saved := make(chan struct{})
+	go func() {
+		sanepanic.SaveGlobalState()
+		close(saved)
+	}()
+	select {
+	case <-saved:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("SaveGlobalState deadlocked with a HandlerFunc writing output")
+	}
+}
+
+func TestClone(t *testing.T) {
+	handled := make(chan sanepanic.Info, 1)
+	original := sanepanic.NewHandler(func(info sanepanic.Info) bool {
+		handled <- info
+		return true
+	})
+	original.SetRedactor(func(info sanepanic.Info) sanepanic.Info {
+		info.Info = "[redacted]"
+		return info
+	})
+	original.SetHistorySize(2)
+
+	clone := original.Clone()
+	defer clone.Done()
+	original.Done()
+
+	clone.ForwardValueSync("password: hunter2")
+
+	if info := <-handled; info.Info != "[redacted]" {
+		t.Errorf("Clone didn't copy the redactor, handled %v", info.Info)
+	}
+	if recent := clone.RecentPanics(); len(recent) != 1 {
+		t.Errorf("Clone didn't copy the history size, retained %v", recent)
+	}
+	if recent := original.RecentPanics(); len(recent) != 0 {
+		t.Errorf("Clone shares its history with the original, which retained %v", recent)
+	}
+}
+
+func TestCloneBatchHandler(t *testing.T) {
+	batches := make(chan []sanepanic.Info, 1)
+	original := sanepanic.NewBatchHandler(func(infos []sanepanic.Info) bool {
+		batches <- infos
+		return true
+	}, time.Millisecond)
+	clone := original.Clone()
+	defer clone.Done()
+	original.Done()
+
+	clone.ForwardValueSync("Oh no!")
+	if batch := <-batches; len(batch) != 1 || batch[0].Info != "Oh no!" {
+		t.Errorf("Clone of a batch handler didn't deliver a batch, got %v", batch)
+	}
+}
+
+func TestReset(t *testing.T) {
+	sanepanic.SetHandlerFunc(func(sanepanic.Info) bool {
+		t.Errorf("Handler ran after Reset")
+		return false
+	})
+	sanepanic.AddHandlerFunc(func(sanepanic.Info) bool {
+		t.Errorf("Registered handler ran after Reset")
+		return false
+	})
+	sanepanic.SetHistorySize(5)
+
+	sanepanic.Reset()
+
+	buf := &bytes.Buffer{}
+	sanepanic.SetOutput(buf)
+	defer sanepanic.Reset()
+
+	sanepanic.ForwardValueSync("Oh no!")
+	if !strings.Contains(buf.String(), "panic: Oh no!") {
+		t.Errorf("Expected the default handler to print the panic, output was:\n%s", buf)
+	}
+	if recent := sanepanic.RecentPanics(); len(recent) != 0 {
+		t.Errorf("History survived Reset: %v", recent)
+	}
+}
@@ -0,0 +1,51 @@
+package sanepanic
+
+import (
+	"context"
+)
+
+// Blocks until the package's listener has handled the next panic. See Handler.WaitForPanic.
+func WaitForPanic(ctx context.Context) (Info, error) {
+	ph := packageHandler()
+	return ph.WaitForPanic(ctx)
+}
+
+// Blocks until the next panic forwarded to this handler has been handled and returns its Info,
+// or returns the context's error if it's cancelled first. Every concurrent caller receives the same next panic.
+// This is mostly useful in tests, or for a supervisor that restarts a subsystem after it panics.
+func (ph *Handler) WaitForPanic(ctx context.Context) (Info, error) {
+	waiter := make(chan Info, 1)
+	ph.waitMu.Lock()
+	ph.waiters = append(ph.waiters, waiter)
+	ph.waitMu.Unlock()
+
+	select {
+	case info := <-waiter:
+		return info, nil
+	case <-ctx.Done():
+		ph.removeWaiter(waiter)
+		return Info{}, ctx.Err()
+	}
+}
+
+func (ph *Handler) removeWaiter(waiter chan Info) {
+	ph.waitMu.Lock()
+	defer ph.waitMu.Unlock()
+	for i, w := range ph.waiters {
+		if w == waiter {
+			ph.waiters = append(ph.waiters[:i], ph.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (ph *Handler) notifyWaiters(info Info) {
+	ph.waitMu.Lock()
+	waiters := ph.waiters
+	ph.waiters = nil
+	ph.waitMu.Unlock()
+
+	for _, waiter := range waiters {
+		waiter <- info // Buffered, and each waiter is only notified once
+	}
+}
@@ -0,0 +1,62 @@
+package sanepanic_test
+
+import (
+	"context"
+	"github.com/Jragonmiris/sanepanic"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWaitForPanic(t *testing.T) {
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		return true
+	})
+	defer ph.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			info, err := ph.WaitForPanic(ctx)
+			if err != nil {
+				t.Errorf("Waiting for a panic failed: %v", err)
+			} else if info.Info != "Oh no!" {
+				t.Errorf("Waiter received %v, expected \"Oh no!\"", info.Info)
+			}
+		}()
+	}
+
+	// Keep panicking until every waiter has registered and seen a panic
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for {
+		select {
+		case <-done:
+			return
+		case <-time.After(10 * time.Millisecond):
+			ph.ForwardValueSync("Oh no!")
+		}
+	}
+}
+
+func TestWaitForPanicCancelled(t *testing.T) {
+	ph := sanepanic.NewHandler(func(sanepanic.Info) bool {
+		return true
+	})
+	defer ph.Done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := ph.WaitForPanic(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected the context's error, got %v", err)
+	}
+}